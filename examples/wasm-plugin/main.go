@@ -0,0 +1,67 @@
+//go:build wasip1
+
+// Example of a promruval WASM plugin checking that critical alerts have the runbook_url annotation.
+//
+// Build it using:
+//
+//	GOOS=wasip1 GOARCH=wasm go build -buildmode=c-shared -o plugin.wasm ./examples/wasm-plugin
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"unsafe"
+)
+
+type input struct {
+	Rule struct {
+		Name        string            `json:"name"`
+		Type        string            `json:"type"`
+		Labels      map[string]string `json:"labels"`
+		Annotations map[string]string `json:"annotations"`
+	} `json:"rule"`
+}
+
+type message struct {
+	Message  string `json:"message"`
+	Severity string `json:"severity,omitempty"`
+}
+
+// buffers keeps the memory shared with promruval referenced, so it is not garbage collected until freed.
+var buffers = map[uint32][]byte{}
+
+//go:wasmexport promruval_allocate
+func allocate(size uint32) uint32 {
+	buf := make([]byte, size+1)
+	ptr := uint32(uintptr(unsafe.Pointer(unsafe.SliceData(buf))))
+	buffers[ptr] = buf[:size]
+	return ptr
+}
+
+//go:wasmexport promruval_free
+func free(ptr uint32) {
+	delete(buffers, ptr)
+}
+
+//go:wasmexport promruval_validate
+func validate(ptr, size uint32) uint64 {
+	messages := []message{}
+	var in input
+	if err := json.Unmarshal(buffers[ptr][:size], &in); err != nil {
+		messages = append(messages, message{Message: fmt.Sprintf("invalid input: %s", err)})
+	}
+	if in.Rule.Type == "alert" && in.Rule.Labels["severity"] == "critical" {
+		if _, ok := in.Rule.Annotations["runbook_url"]; !ok {
+			messages = append(messages, message{Message: fmt.Sprintf("critical alert %s must have the runbook_url annotation", in.Rule.Name)})
+		}
+		if _, ok := in.Rule.Annotations["dashboard"]; !ok {
+			messages = append(messages, message{Message: "consider adding the dashboard annotation", Severity: "warning"})
+		}
+	}
+	output, _ := json.Marshal(messages)
+	outputPtr := allocate(uint32(len(output)))
+	copy(buffers[outputPtr], output)
+	return uint64(outputPtr)<<32 | uint64(len(output))
+}
+
+func main() {}
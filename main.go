@@ -1,16 +1,24 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
 	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
 	"github.com/alecthomas/kingpin/v2"
 	"github.com/fusakla/promruval/v3/pkg/config"
 	"github.com/fusakla/promruval/v3/pkg/extractvalidators"
+	"github.com/fusakla/promruval/v3/pkg/prometheus"
 	"github.com/fusakla/promruval/v3/pkg/report"
+	"github.com/fusakla/promruval/v3/pkg/unmarshaler"
 	"github.com/fusakla/promruval/v3/pkg/validate"
+	"github.com/fusakla/promruval/v3/pkg/validationrule"
 	log "github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
 )
 
 var (
@@ -23,11 +31,16 @@ var (
 	app                 = kingpin.New("promruval", "Prometheus rules validation tool.")
 	validateConfigFiles = app.Flag("config-file", "Path to validation config file. Can be passed multiple times, only validationRules will be reflected from the additional configs.").Short('c').ExistingFiles()
 	debug               = app.Flag("debug", "Enable debug logging.").Bool()
+	jsonnetExtStr       = app.Flag("jsonnet-ext-str", "Jsonnet external variable as a string, used when evaluating jsonnet config and rule files. Can be passed multiple times.").PlaceHolder("KEY=VALUE").StringMap()
+	jsonnetExtCode      = app.Flag("jsonnet-ext-code", "Jsonnet external variable as jsonnet code, used when evaluating jsonnet config and rule files. Can be passed multiple times.").PlaceHolder("KEY=CODE").StringMap()
+	jsonnetTLAStr       = app.Flag("jsonnet-tla-str", "Jsonnet top-level argument as a string, used when evaluating jsonnet config and rule files. Can be passed multiple times.").PlaceHolder("KEY=VALUE").StringMap()
+	jsonnetTLACode      = app.Flag("jsonnet-tla-code", "Jsonnet top-level argument as jsonnet code, used when evaluating jsonnet config and rule files. Can be passed multiple times.").PlaceHolder("KEY=CODE").StringMap()
+	jsonnetJPath        = app.Flag("jsonnet-jpath", "Jsonnet library search directory, the rightmost wins. Can be passed multiple times.").Short('J').Strings()
 
 	versionCmd = app.Command("version", "Print version and build information.")
 
 	validateCmd            = app.Command("validate", "Validate Prometheus rule files in YAML or jsonnet format using validation rules from config file(s).")
-	filePaths              = validateCmd.Arg("path", "Rule file paths to be validated (.yaml, .yml or .jsonnet), can use even double star globs or ~. Will be expanded if not done by bash.").Required().Strings()
+	filePaths              = validateCmd.Arg("path", "Rule file paths to be validated (.yaml, .yml, .jsonnet or .libsonnet), can use even double star globs or ~. Will be expanded if not done by bash.").Required().Strings()
 	disabledRules          = validateCmd.Flag("disable-rule", "Allows to disable any validation rules by it's name. Can be passed multiple times.").Short('d').Strings()
 	enabledRules           = validateCmd.Flag("enable-rule", "Only enable these validation rules. Can be passed multiple times.").Short('e').Strings()
 	validationOutputFormat = validateCmd.Flag("output", "Format of the output.").Short('o').PlaceHolder("[text,json,yaml]").Default("text").Enum("text", "json", "yaml")
@@ -36,16 +49,211 @@ var (
 	supportMimir           = validateCmd.Flag("support-mimir", "Support Mimir rules format.").Bool()
 	supportThanos          = validateCmd.Flag("support-thanos", "Support Thanos rules format.").Bool()
 	disableParallelization = validateCmd.Flag("disable-parallelization", "Disable parallelization of validation checks.").Bool()
+	watch                  = validateCmd.Flag("watch", "Keep running and re-validate rule files whenever they or the config files change.").Bool()
 
 	docsCmd          = app.Command("validation-docs", "Print human readable form of the validation rules from config file.")
 	docsOutputFormat = docsCmd.Flag("output", "Format of the output.").Short('o').PlaceHolder("[text,markdown,html]").Default("text").Enum("text", "markdown", "html")
+
+	cacheCmd            = app.Command("cache", "Inspect and manage the query cache of the configured Prometheus and backends.")
+	cacheStatsCmd       = cacheCmd.Command("stats", "Show statistics of the cache including the hit rate of the last run.")
+	cacheStatsOutput    = cacheStatsCmd.Flag("output", "Format of the output.").Short('o').PlaceHolder("[text,json,yaml]").Default("text").Enum("text", "json", "yaml")
+	cachePruneCmd       = cacheCmd.Command("prune", "Delete the expired entries from the cache.")
+	cachePruneOlderThan = cachePruneCmd.Flag("older-than", "Delete entries older than the duration instead of the ones expired by the maxCacheAge.").Duration()
+	cacheWarmCmd        = cacheCmd.Command("warm", "Fill the cache with the Prometheus queries needed by the validation rules for the rule files, without validating them.")
+	cacheWarmPaths      = cacheWarmCmd.Arg("path", "Rule file paths (.yaml, .yml, .jsonnet or .libsonnet), can use even double star globs or ~.").Required().Strings()
+	cacheWarmLoki       = cacheWarmCmd.Flag("support-loki", "Support Loki rules format.").Bool()
+	cacheWarmMimir      = cacheWarmCmd.Flag("support-mimir", "Support Mimir rules format.").Bool()
+	cacheWarmThanos     = cacheWarmCmd.Flag("support-thanos", "Support Thanos rules format.").Bool()
+	cacheExportCmd      = cacheCmd.Command("export", "Export the cache to a file.")
+	cacheExportFile     = cacheExportCmd.Arg("file", "File to export the cache to, - for stdout.").Required().String()
+	cacheExportBackend  = cacheExportCmd.Flag("backend", "Name of the Prometheus backend to export the cache of, default for the prometheus section. Required if there are multiple caches.").String()
+	cacheImportCmd      = cacheCmd.Command("import", "Merge the exported cache file to the cache, newer entries take precedence.")
+	cacheImportFile     = cacheImportCmd.Arg("file", "Exported cache file.").Required().ExistingFile()
+	cacheImportBackend  = cacheImportCmd.Flag("backend", "Name of the Prometheus backend to import the cache to, default for the prometheus section. Required if there are multiple caches.").String()
 )
 
+const defaultPrometheusName = "default"
+
 func exitWithError(err error) {
 	log.Error(err)
 	os.Exit(1)
 }
 
+func loadConfig() (*config.Config, []*validationrule.ValidationRule, error) {
+	jsonnetConfig := config.JsonnetConfig{
+		ExtStr:  *jsonnetExtStr,
+		ExtCode: *jsonnetExtCode,
+		TLAStr:  *jsonnetTLAStr,
+		TLACode: *jsonnetTLACode,
+		JPath:   *jsonnetJPath,
+	}
+	validationConfig, err := config.LoadConfigurationWithJsonnet(*validateConfigFiles, jsonnetConfig)
+	if err != nil {
+		return nil, nil, err
+	}
+	validationRules, err := extractvalidators.ValidationRulesFromConfig(validationConfig, *disabledRules, *enabledRules)
+	if err != nil {
+		return nil, nil, err
+	}
+	return validationConfig, validationRules, nil
+}
+
+func renderValidationReport(validationReport *report.ValidationReport) (string, error) {
+	switch *validationOutputFormat {
+	case "json":
+		return validationReport.AsJSON()
+	case "yaml":
+		return validationReport.AsYaml()
+	default:
+		return validationReport.AsText(2, *color)
+	}
+}
+
+func setupLogging() {
+	log.SetLevel(log.InfoLevel)
+	log.SetOutput(os.Stderr)
+	log.SetFormatter(&log.TextFormatter{FullTimestamp: true, DisableLevelTruncation: true})
+	if *debug {
+		log.SetLevel(log.DebugLevel)
+	}
+}
+
+type namedPrometheusConfig struct {
+	name   string
+	config config.PrometheusConfig
+}
+
+// prometheusCaches returns configs of the Prometheus and backends with enabled cache, limited to the backend if set.
+// Configs sharing the same cache are returned only once.
+func prometheusCaches(validationConfig *config.Config, backend string) ([]namedPrometheusConfig, error) {
+	var configs []namedPrometheusConfig
+	if validationConfig.Prometheus.URL != "" {
+		configs = append(configs, namedPrometheusConfig{name: defaultPrometheusName, config: validationConfig.Prometheus})
+	}
+	for _, b := range validationConfig.PrometheusBackends {
+		configs = append(configs, namedPrometheusConfig{name: b.Name, config: b.Prometheus})
+	}
+	var caches []namedPrometheusConfig
+	seen := map[string]bool{}
+	for _, c := range configs {
+		if c.config.DisableCache || c.config.Mode != config.PrometheusModeLive || (backend != "" && c.name != backend) {
+			continue
+		}
+		location := prometheus.CacheLocation(c.config)
+		if seen[location] {
+			continue
+		}
+		seen[location] = true
+		caches = append(caches, c)
+	}
+	if len(caches) == 0 {
+		if backend != "" {
+			return nil, fmt.Errorf("no cache of the prometheus backend %s is configured", backend)
+		}
+		return nil, fmt.Errorf("no prometheus cache is configured")
+	}
+	return caches, nil
+}
+
+// singlePrometheusCache returns the config of the only cache or of the selected backend.
+func singlePrometheusCache(validationConfig *config.Config, backend string) (config.PrometheusConfig, error) {
+	caches, err := prometheusCaches(validationConfig, backend)
+	if err != nil {
+		return config.PrometheusConfig{}, err
+	}
+	if len(caches) > 1 {
+		return config.PrometheusConfig{}, fmt.Errorf("multiple caches are configured, select one using the --backend flag")
+	}
+	return caches[0].config, nil
+}
+
+func runCacheCommand(currentCommand string, validationConfig *config.Config, validationRules []*validationrule.ValidationRule) error {
+	switch currentCommand {
+	case cacheStatsCmd.FullCommand():
+		caches, err := prometheusCaches(validationConfig, "")
+		if err != nil {
+			return err
+		}
+		var allStats []prometheus.CacheStats
+		for _, c := range caches {
+			stats, err := prometheus.ReadCacheStats(c.config)
+			if err != nil {
+				return fmt.Errorf("failed to read cache of %s: %w", c.name, err)
+			}
+			allStats = append(allStats, stats)
+		}
+		switch *cacheStatsOutput {
+		case "json":
+			output, err := json.MarshalIndent(allStats, "", "  ")
+			if err != nil {
+				return err
+			}
+			fmt.Println(string(output))
+		case "yaml":
+			output, err := yaml.Marshal(allStats)
+			if err != nil {
+				return err
+			}
+			fmt.Print(string(output))
+		default:
+			for _, stats := range allStats {
+				fmt.Print(stats)
+			}
+		}
+	case cachePruneCmd.FullCommand():
+		caches, err := prometheusCaches(validationConfig, "")
+		if err != nil {
+			return err
+		}
+		for _, c := range caches {
+			pruned, err := prometheus.PruneCache(c.config, *cachePruneOlderThan)
+			if err != nil {
+				return fmt.Errorf("failed to prune cache of %s: %w", c.name, err)
+			}
+			fmt.Printf("Pruned %d entries from cache %s\n", pruned, prometheus.CacheLocation(c.config))
+		}
+	case cacheWarmCmd.FullCommand():
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		formats := unmarshaler.Formats{Loki: *cacheWarmLoki, Mimir: *cacheWarmMimir, Thanos: *cacheWarmThanos}
+		stats, err := validate.WarmCache(ctx, *cacheWarmPaths, validationConfig, validationRules, formats)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Cache warmed using %d Prometheus requests, %d entries were already cached\n", stats.Requests, stats.CacheHits)
+	case cacheExportCmd.FullCommand():
+		promConfig, err := singlePrometheusCache(validationConfig, *cacheExportBackend)
+		if err != nil {
+			return err
+		}
+		if *cacheExportFile == "-" {
+			return prometheus.ExportCache(promConfig, os.Stdout)
+		}
+		f, err := os.Create(*cacheExportFile)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		return prometheus.ExportCache(promConfig, f)
+	case cacheImportCmd.FullCommand():
+		promConfig, err := singlePrometheusCache(validationConfig, *cacheImportBackend)
+		if err != nil {
+			return err
+		}
+		f, err := os.Open(*cacheImportFile)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		imported, err := prometheus.ImportCache(promConfig, f)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Imported %d entries to cache %s\n", imported, prometheus.CacheLocation(promConfig))
+	}
+	return nil
+}
+
 func main() {
 	currentCommand := kingpin.MustParse(app.Parse(os.Args[1:]))
 
@@ -58,12 +266,7 @@ func main() {
 		app.Fatalf("required flag --config-file not provided, try --help")
 	}
 
-	validationConfig, err := config.LoadConfiguration(*validateConfigFiles)
-	if err != nil {
-		exitWithError(err)
-	}
-
-	validationRules, err := extractvalidators.ValidationRulesFromConfig(validationConfig, *disabledRules, *enabledRules)
+	validationConfig, validationRules, err := loadConfig()
 	if err != nil {
 		exitWithError(err)
 	}
@@ -79,28 +282,38 @@ func main() {
 			exitWithError(err)
 		}
 		fmt.Println(output)
+	case cacheStatsCmd.FullCommand(), cachePruneCmd.FullCommand(), cacheWarmCmd.FullCommand(), cacheExportCmd.FullCommand(), cacheImportCmd.FullCommand():
+		setupLogging()
+		if err := runCacheCommand(currentCommand, validationConfig, validationRules); err != nil {
+			exitWithError(err)
+		}
 	case validateCmd.FullCommand():
-		log.SetLevel(log.InfoLevel)
-		log.SetOutput(os.Stderr)
-		log.SetFormatter(&log.TextFormatter{FullTimestamp: true, DisableLevelTruncation: true})
-		if *debug {
-			log.SetLevel(log.DebugLevel)
+		setupLogging()
+
+		formats := unmarshaler.Formats{Loki: *supportLoki, Mimir: *supportMimir, Thanos: *supportThanos}
+		if *watch {
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+			err := validate.Watch(ctx, *filePaths, *validateConfigFiles, loadConfig, formats, *disableParallelization, func(validationReport *report.ValidationReport) {
+				output, err := renderValidationReport(validationReport)
+				if err != nil {
+					log.WithError(err).Error("failed to render validation report")
+					return
+				}
+				fmt.Println(output)
+			})
+			if err != nil {
+				exitWithError(err)
+			}
+			return
 		}
 
-		validationReport, err := validate.Cmd(*filePaths, validationConfig, validationRules, *supportLoki, *supportMimir, *supportThanos, *disableParallelization)
+		validationReport, err := validate.CmdWithFormats(*filePaths, validationConfig, validationRules, formats, *disableParallelization)
 		if err != nil {
 			exitWithError(err)
 		}
 
-		var output string
-		switch *validationOutputFormat {
-		case "text":
-			output, err = validationReport.AsText(2, *color)
-		case "json":
-			output, err = validationReport.AsJSON()
-		case "yaml":
-			output, err = validationReport.AsYaml()
-		}
+		output, err := renderValidationReport(validationReport)
 		if err != nil {
 			exitWithError(err)
 		}
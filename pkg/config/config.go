@@ -1,18 +1,24 @@
 package config
 
 import (
+	"bytes"
 	"fmt"
 	"io"
+	"maps"
 	"os"
 	"path"
+	"path/filepath"
+	"slices"
 	"strings"
 	"sync"
 	"time"
 
 	"gopkg.in/yaml.v3"
 
+	"github.com/bmatcuk/doublestar/v4"
 	"github.com/creasty/defaults"
-	"github.com/google/go-jsonnet"
+	prom_config "github.com/prometheus/common/config"
+	log "github.com/sirupsen/logrus"
 )
 
 const (
@@ -25,17 +31,19 @@ const (
 
 var ValidationScopes = []ValidationScope{GroupScope, AlertScope, RecordingRuleScope, AllRulesScope}
 
-// Ugly hack with a global variable to be able to use it in UnmarshalYAML.
-// Not sure how to better propagate some context to the UnmarshalYAML function.
+// baseDirPath is the directory of the last loaded config file, kept only for the deprecated BaseDirPath.
 var (
-	configDir    string
-	configDirMtx sync.Mutex
+	baseDirPath    string
+	baseDirPathMtx sync.Mutex
 )
 
+// BaseDirPath returns the directory of the last loaded config file.
+//
+// Deprecated: Use the ValidatorConfig.BaseDir, which is the directory of the config file the validator was loaded from.
 func BaseDirPath() string {
-	configDirMtx.Lock()
-	defer configDirMtx.Unlock()
-	return configDir
+	baseDirPathMtx.Lock()
+	defer baseDirPathMtx.Unlock()
+	return baseDirPath
 }
 
 func NewLoader(cfgPath string) Loader {
@@ -44,47 +52,207 @@ func NewLoader(cfgPath string) Loader {
 
 type Loader struct {
 	ConfigPath string
+	// Jsonnet configures evaluation of the jsonnet config files, it is also merged to the loaded config for evaluation of the jsonnet rule files.
+	Jsonnet JsonnetConfig
 }
 
 func (l *Loader) Load() (*Config, error) {
+	loaded := map[string]struct{}{}
+	validationConfig, err := loadConfigFile(l.ConfigPath, l.Jsonnet, nil, loaded)
+	if err != nil {
+		return nil, err
+	}
+	validationConfig.Jsonnet = validationConfig.Jsonnet.Merge(l.Jsonnet)
+	if err := validationConfig.resolveTemplates(); err != nil {
+		return nil, fmt.Errorf("error loading config file %s: %w", l.ConfigPath, err)
+	}
+	validationConfig.Files = slices.Sorted(maps.Keys(loaded))
+	return validationConfig, nil
+}
+
+// loadConfigFile loads the config file and all the configs it includes, includeStack is used to detect cycles,
+// already loaded files are skipped, so the validation rules of files included multiple times are not duplicated.
+func loadConfigFile(configPath string, jsonnetConfig JsonnetConfig, includeStack []string, loaded map[string]struct{}) (*Config, error) {
+	absPath, err := filepath.Abs(configPath)
+	if err != nil {
+		return nil, fmt.Errorf("resolve config file path %s: %w", configPath, err)
+	}
+	if slices.Contains(includeStack, absPath) {
+		return nil, fmt.Errorf("config include cycle detected: %s -> %s", strings.Join(includeStack, " -> "), absPath)
+	}
+	includeStack = append(includeStack, absPath)
+	loaded[absPath] = struct{}{}
+
+	validationConfig, err := decodeConfigFile(configPath, jsonnetConfig)
+	if err != nil {
+		return nil, err
+	}
+	configDir := path.Dir(configPath)
+	if err := validationConfig.resolveRelativePaths(configDir); err != nil {
+		return nil, err
+	}
+	baseDirPathMtx.Lock()
+	baseDirPath = configDir
+	baseDirPathMtx.Unlock()
+	for i := range validationConfig.ValidationRules {
+		validationConfig.ValidationRules[i].Source = configPath
+	}
+
+	var includedRules []ValidationRule
+	var includedBackends []PrometheusBackend
+	for _, include := range validationConfig.Include {
+		includePath := path.Join(configDir, include)
+		absIncludePath, err := filepath.Abs(includePath)
+		if err != nil {
+			return nil, fmt.Errorf("resolve included config file path %s: %w", includePath, err)
+		}
+		if _, ok := loaded[absIncludePath]; ok && !slices.Contains(includeStack, absIncludePath) {
+			log.Debugf("skipping config file %s included from %s, it was already loaded", includePath, configPath)
+			continue
+		}
+		includedConfig, err := loadConfigFile(includePath, jsonnetConfig, includeStack, loaded)
+		if err != nil {
+			return nil, fmt.Errorf("loading config file %s included from %s: %w", includePath, configPath, err)
+		}
+		// Settings of the including file take precedence over the included ones.
+		if validationConfig.Prometheus.URL == "" && includedConfig.Prometheus.URL != "" {
+			validationConfig.Prometheus = includedConfig.Prometheus
+		}
+		if validationConfig.CustomExcludeAnnotation == "" {
+			validationConfig.CustomExcludeAnnotation = includedConfig.CustomExcludeAnnotation
+		}
+		if validationConfig.CustomDisableComment == "" {
+			validationConfig.CustomDisableComment = includedConfig.CustomDisableComment
+		}
+		validationConfig.Jsonnet = includedConfig.Jsonnet.Merge(validationConfig.Jsonnet)
+		if err := validationConfig.mergeDefinitions(includedConfig.Definitions); err != nil {
+			return nil, fmt.Errorf("loading config file %s included from %s: %w", includePath, configPath, err)
+		}
+		includedRules = append(includedRules, includedConfig.ValidationRules...)
+		includedBackends = append(includedBackends, includedConfig.PrometheusBackends...)
+	}
+	validationConfig.ValidationRules = append(includedRules, validationConfig.ValidationRules...)
+	validationConfig.PrometheusBackends = append(includedBackends, validationConfig.PrometheusBackends...)
+	return validationConfig, nil
+}
+
+func decodeConfigFile(configPath string, jsonnetConfig JsonnetConfig) (*Config, error) {
 	var configFile io.ReadCloser
-	configFile, err := os.Open(l.ConfigPath)
+	configFile, err := os.Open(configPath)
 	if err != nil {
 		return nil, fmt.Errorf("open config file: %w", err)
 	}
 	defer configFile.Close()
-	configDirMtx.Lock()
-	configDir = path.Dir(l.ConfigPath)
-	defer func() {
-		configDirMtx.Unlock()
-	}()
 	validationConfig := Config{}
 
 	// If the config file is a jsonnet file, evaluate it first
-	if strings.HasSuffix(l.ConfigPath, ".jsonnet") {
-		jsonnetVM := jsonnet.MakeVM()
-		jsonStr, err := jsonnetVM.EvaluateFile(l.ConfigPath)
+	if strings.HasSuffix(configPath, ".jsonnet") {
+		jsonnetVM := jsonnetConfig.NewVM(configPath)
+		jsonStr, err := jsonnetVM.EvaluateFile(configPath)
 		if err != nil {
-			return nil, fmt.Errorf("evaluating jsonnet in config file %s: %w", l.ConfigPath, err)
+			return nil, fmt.Errorf("evaluating jsonnet in config file %s: %w", configPath, err)
 		}
 		configFile = io.NopCloser(strings.NewReader(jsonStr))
 	}
 
-	decoder := yaml.NewDecoder(configFile)
+	var configNode yaml.Node
+	if err := yaml.NewDecoder(configFile).Decode(&configNode); err != nil {
+		return nil, fmt.Errorf("loading config file: %w", err)
+	}
+	if err := expandEnvNode(&configNode); err != nil {
+		return nil, fmt.Errorf("expanding environment variables in config file %s: %w", configPath, err)
+	}
+	// The node is encoded back, because decoding the node directly does not support the strict checking of known fields.
+	// Lines in the decoding errors are restored to the ones in the original file.
+	expandedConfig, err := yaml.Marshal(&configNode)
+	if err != nil {
+		return nil, fmt.Errorf("loading config file: %w", err)
+	}
+	decoder := yaml.NewDecoder(bytes.NewReader(expandedConfig))
 	decoder.KnownFields(true)
 	if err := decoder.Decode(&validationConfig); err != nil {
-		return nil, fmt.Errorf("loading config file: %w", err)
+		return nil, fmt.Errorf("loading config file: %w", restoreErrorLines(err, expandedConfig, &configNode))
+	}
+	for _, include := range validationConfig.Include {
+		if path.IsAbs(include) {
+			return nil, fmt.Errorf("included config file %s must be a relative path to the config file", include)
+		}
 	}
 	return &validationConfig, nil
 }
 
+// resolveRelativePaths resolves all the paths in the config relative to the directory of the config file.
+func (c *Config) resolveRelativePaths(configDir string) error {
+	c.Prometheus.resolveRelativePaths(configDir)
+	for i := range c.PrometheusBackends {
+		c.PrometheusBackends[i].Prometheus.resolveRelativePaths(configDir)
+	}
+	for i, jpath := range c.Jsonnet.JPath {
+		if !path.IsAbs(jpath) {
+			c.Jsonnet.JPath[i] = path.Join(configDir, jpath)
+		}
+	}
+	for i := range c.ValidationRules {
+		for _, validators := range [][]ValidatorConfig{c.ValidationRules[i].OnlyIf, c.ValidationRules[i].Validations} {
+			for j := range validators {
+				if err := validators[j].ResolveRelativePaths(configDir); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	for _, validators := range c.Definitions {
+		for j := range validators {
+			if err := validators[j].ResolveRelativePaths(configDir); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// mergeDefinitions adds the definitions from other config file, the definition names must be unique across all the config files.
+func (c *Config) mergeDefinitions(definitions map[string][]ValidatorConfig) error {
+	for name, validators := range definitions {
+		if _, ok := c.Definitions[name]; ok {
+			return fmt.Errorf("definition `%s` is defined multiple times", name)
+		}
+		if c.Definitions == nil {
+			c.Definitions = map[string][]ValidatorConfig{}
+		}
+		c.Definitions[name] = validators
+	}
+	return nil
+}
+
 type Config struct {
-	CustomExcludeAnnotation string           `yaml:"customExcludeAnnotation"`
-	CustomDisableComment    string           `yaml:"customDisableComment"`
-	ValidationRules         []ValidationRule `yaml:"validationRules"`
-	Prometheus              PrometheusConfig `yaml:"prometheus"`
+	// Include lists paths to other config files, relative to this one, whose validation rules are loaded before the ones of this file.
+	Include []string `yaml:"include"`
+	// Files are absolute paths of all the loaded config files including the included ones.
+	Files []string `yaml:"-"`
+	// Definitions are named lists of validators, which can be referenced from the validators using the `definition` field.
+	Definitions             map[string][]ValidatorConfig `yaml:"definitions"`
+	CustomExcludeAnnotation string                       `yaml:"customExcludeAnnotation"`
+	CustomDisableComment    string                       `yaml:"customDisableComment"`
+	ValidationRules         []ValidationRule             `yaml:"validationRules"`
+	Prometheus              PrometheusConfig             `yaml:"prometheus"`
+	// PrometheusBackends are named Prometheus instances used instead of the default one for the groups and rules matching their selector.
+	PrometheusBackends []PrometheusBackend `yaml:"prometheusBackends"`
+	// Jsonnet configures evaluation of the jsonnet rule files.
+	Jsonnet JsonnetConfig `yaml:"jsonnet"`
 }
 
+// defaultCacheFile is the default of the PrometheusConfig.CacheFile.
+const defaultCacheFile = ".promruval_cache.json"
+
+// Modes of the PrometheusConfig, the live mode queries the Prometheus, record mode stores all the responses to the fixtures directory
+// and replay mode serves the stored responses without any requests to the Prometheus.
+const (
+	PrometheusModeLive   = ""
+	PrometheusModeRecord = "record"
+	PrometheusModeReplay = "replay"
+)
+
 type PrometheusConfig struct {
 	URL                   string            `yaml:"url"`
 	Timeout               time.Duration     `yaml:"timeout" default:"30s"`
@@ -96,6 +264,38 @@ type PrometheusConfig struct {
 	QueryOffset           time.Duration     `yaml:"queryOffset,omitempty" default:"1m"`
 	QueryLookback         time.Duration     `yaml:"queryLookback,omitempty" default:"20m"`
 	HTTPHeaders           map[string]string `yaml:"httpHeaders,omitempty"`
+	// MaxInFlightQueries limits the number of concurrent requests to the Prometheus, 0 means unlimited.
+	MaxInFlightQueries int `yaml:"maxInFlightQueries,omitempty" default:"10"`
+	// MaxRetries is the number of retries of requests failed with 429, 502, 503, 504 or a network timeout, 0 disables retries.
+	MaxRetries int `yaml:"maxRetries,omitempty" default:"3"`
+	// RetryMinBackoff and RetryMaxBackoff bound the exponential backoff between the retries.
+	RetryMinBackoff time.Duration `yaml:"retryMinBackoff,omitempty" default:"500ms"`
+	RetryMaxBackoff time.Duration `yaml:"retryMaxBackoff,omitempty" default:"10s"`
+	// MaxQPS limits the rate of requests including the retries, 0 means unlimited.
+	MaxQPS float64 `yaml:"maxQps,omitempty"`
+	// CacheDir stores the cache as one file per Prometheus and source tenants in the directory instead of the CacheFile.
+	CacheDir string `yaml:"cacheDir,omitempty"`
+	// Mode is one of the PrometheusModeLive, PrometheusModeRecord or PrometheusModeReplay.
+	Mode string `yaml:"mode,omitempty"`
+	// FixturesDir is the directory with the recorded responses used by the record and replay modes.
+	FixturesDir string `yaml:"fixturesDir,omitempty"`
+	// HTTPHeadersFile maps the HTTP header names to relative paths of files containing their values.
+	HTTPHeadersFile map[string]string `yaml:"httpHeadersFile,omitempty"`
+	// HTTPClientConfig is the HTTP client configuration in the same format as used by Prometheus, for example for TLS, basic auth, OAuth2 or proxy.
+	HTTPClientConfig prom_config.HTTPClientConfig `yaml:"httpClientConfig,omitempty"`
+}
+
+func (c *PrometheusConfig) resolveRelativePaths(configDir string) {
+	if c.BearerTokenFile != "" {
+		c.BearerTokenFile = path.Join(configDir, c.BearerTokenFile)
+	}
+	c.HTTPClientConfig.SetDirectory(configDir)
+	for header, file := range c.HTTPHeadersFile {
+		c.HTTPHeadersFile[header] = path.Join(configDir, file)
+	}
+	if c.FixturesDir != "" && !path.IsAbs(c.FixturesDir) {
+		c.FixturesDir = path.Join(configDir, c.FixturesDir)
+	}
 }
 
 func (c *PrometheusConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
@@ -103,19 +303,85 @@ func (c *PrometheusConfig) UnmarshalYAML(unmarshal func(interface{}) error) erro
 	if err != nil {
 		return err
 	}
+	c.HTTPClientConfig = prom_config.DefaultHTTPClientConfig
 
 	type plain PrometheusConfig
 	if err := unmarshal((*plain)(c)); err != nil {
 		return err
 	}
 
-	if c.BearerTokenFile != "" {
-		if path.IsAbs(c.BearerTokenFile) {
-			return fmt.Errorf("`bearerTokenFile` must be a relative path to the config file")
+	if c.BearerTokenFile != "" && path.IsAbs(c.BearerTokenFile) {
+		return fmt.Errorf("`bearerTokenFile` must be a relative path to the config file")
+	}
+	if c.BearerTokenFile != "" && (c.HTTPClientConfig.Authorization != nil || c.HTTPClientConfig.BasicAuth != nil || c.HTTPClientConfig.OAuth2 != nil) {
+		return fmt.Errorf("`bearerTokenFile` cannot be used together with the authentication configured in the `httpClientConfig`")
+	}
+	for header, file := range c.HTTPHeadersFile {
+		if path.IsAbs(file) {
+			return fmt.Errorf("file of the `%s` header in `httpHeadersFile` must be a relative path to the config file", header)
 		}
-		c.BearerTokenFile = path.Join(configDir, c.BearerTokenFile)
+		if _, ok := c.HTTPHeaders[header]; ok {
+			return fmt.Errorf("header `%s` cannot be set in both `httpHeaders` and `httpHeadersFile`", header)
+		}
+	}
+	switch c.Mode {
+	case PrometheusModeLive:
+	case PrometheusModeRecord, PrometheusModeReplay:
+		if c.FixturesDir == "" {
+			return fmt.Errorf("`fixturesDir` must be set in the `%s` mode", c.Mode)
+		}
+	default:
+		return fmt.Errorf("invalid `mode` %s, must be one of: %s, %s", c.Mode, PrometheusModeRecord, PrometheusModeReplay)
 	}
+	if c.MaxRetries < 0 || c.MaxQPS < 0 {
+		return fmt.Errorf("`maxRetries` and `maxQps` cannot be negative")
+	}
+	if c.RetryMinBackoff <= 0 || c.RetryMaxBackoff < c.RetryMinBackoff {
+		return fmt.Errorf("`retryMinBackoff` must be positive and not greater than `retryMaxBackoff`")
+	}
+
+	return nil
+}
+
+// PrometheusBackend is a named Prometheus instance used by the validators for the groups and rules matching its selector.
+type PrometheusBackend struct {
+	Name       string                    `yaml:"name"`
+	Selector   PrometheusBackendSelector `yaml:"selector"`
+	Prometheus PrometheusConfig          `yaml:"prometheus"`
+}
+
+// PrometheusBackendSelector selects the groups and rules, all the set conditions must match. Empty selector matches everything.
+type PrometheusBackendSelector struct {
+	// Paths are double star globs matched against the path of the rule file, any of them must match.
+	Paths []string `yaml:"paths"`
+	// GroupLabels must be all set on the group with the same values.
+	GroupLabels map[string]string `yaml:"groupLabels"`
+	// SourceTenants matches groups with any of the source tenants.
+	SourceTenants []string `yaml:"sourceTenants"`
+	// Annotations must be all set on the rule with the same values, so the selector never matches the group level validations.
+	Annotations map[string]string `yaml:"annotations"`
+}
 
+func (b *PrometheusBackend) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type plain PrometheusBackend
+	if err := unmarshal((*plain)(b)); err != nil {
+		return err
+	}
+	if b.Name == "" {
+		return fmt.Errorf("prometheus backend must have a name")
+	}
+	if b.Prometheus.URL == "" {
+		return fmt.Errorf("prometheus backend `%s` must have the `prometheus.url` set", b.Name)
+	}
+	for _, pattern := range b.Selector.Paths {
+		if !doublestar.ValidatePattern(pattern) {
+			return fmt.Errorf("invalid path pattern `%s` in the selector of the prometheus backend `%s`", pattern, b.Name)
+		}
+	}
+	// Each backend needs its own cache file, so they do not overwrite each other.
+	if b.Prometheus.CacheFile == defaultCacheFile {
+		b.Prometheus.CacheFile = fmt.Sprintf(".promruval_cache_%s.json", b.Name)
+	}
 	return nil
 }
 
@@ -124,13 +390,53 @@ type ValidationRule struct {
 	Scope       ValidationScope   `yaml:"scope"`
 	OnlyIf      []ValidatorConfig `yaml:"onlyIf"`
 	Validations []ValidatorConfig `yaml:"validations"`
+	// Double star globs matched against the path of the validated rule file, limiting which files the rule applies to.
+	IncludePaths []string `yaml:"includePaths"`
+	ExcludePaths []string `yaml:"excludePaths"`
+	// Extends is the name of other validation rule whose scope, validators and paths are inherited by this rule.
+	Extends string `yaml:"extends"`
+	// Abstract rules are not used for validation, they only serve as a template for other rules extending them.
+	Abstract bool `yaml:"abstract"`
+	// Source is the path to the config file the rule was loaded from.
+	Source string `yaml:"-"`
 }
 
+func (r *ValidationRule) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type plain ValidationRule
+	if err := unmarshal((*plain)(r)); err != nil {
+		return err
+	}
+	for _, pattern := range slices.Concat(r.IncludePaths, r.ExcludePaths) {
+		if !doublestar.ValidatePattern(pattern) {
+			return fmt.Errorf("invalid path pattern `%s` in the validation rule `%s`", pattern, r.Name)
+		}
+	}
+	return nil
+}
+
+// Logical operators which can be used instead of the validator type to compose other validators.
+const (
+	AllOfOperator = "allOf"
+	AnyOfOperator = "anyOf"
+	NotOperator   = "not"
+)
+
 type ValidatorConfig struct {
 	ValidatorType     string    `yaml:"type"`
 	AdditionalDetails string    `yaml:"additionalDetails"`
 	Params            yaml.Node `yaml:"params"`
 	ParamsFromFile    string    `yaml:"paramsFromFile"`
+	// BaseDir is the directory of the config file the validator was loaded from, paths in params are relative to it.
+	BaseDir string `yaml:"-"`
+	// ID identifies the validator, so it can be overridden in the validation rules extending the rule it is defined in.
+	ID string `yaml:"id"`
+
+	// Only one of the ValidatorType, the logical operators or the Definition can be set.
+	AllOf []ValidatorConfig `yaml:"allOf"`
+	AnyOf []ValidatorConfig `yaml:"anyOf"`
+	Not   *ValidatorConfig  `yaml:"not"`
+	// Definition is a name of the definition whose validators are used in place of this one.
+	Definition string `yaml:"definition"`
 }
 
 func (c *ValidatorConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
@@ -146,18 +452,95 @@ func (c *ValidatorConfig) UnmarshalYAML(unmarshal func(interface{}) error) error
 		if path.IsAbs(c.ParamsFromFile) {
 			return fmt.Errorf("`paramsFromFile` must be a relative path to the config file")
 		}
-		fileData, err := os.ReadFile(path.Join(configDir, c.ParamsFromFile))
-		if err != nil {
-			return fmt.Errorf("cannot read params from file %s: %w", c.ParamsFromFile, err)
+	}
+	set := 0
+	for _, isSet := range []bool{c.ValidatorType != "", c.AllOf != nil, c.AnyOf != nil, c.Not != nil, c.Definition != ""} {
+		if isSet {
+			set++
 		}
-		err = yaml.Unmarshal(fileData, &c.Params)
-		if err != nil {
-			return err
+	}
+	if set != 1 {
+		return fmt.Errorf("exactly one of `type`, `%s`, `%s`, `%s` or `definition` must be set", AllOfOperator, AnyOfOperator, NotOperator)
+	}
+	if c.Definition != "" {
+		if !c.Params.IsZero() || c.ParamsFromFile != "" || c.AdditionalDetails != "" || c.ID != "" {
+			return fmt.Errorf("`params`, `paramsFromFile`, `additionalDetails` and `id` cannot be used with the `definition`")
+		}
+		return nil
+	}
+	if c.ValidatorType == "" {
+		if !c.Params.IsZero() || c.ParamsFromFile != "" {
+			return fmt.Errorf("`params` and `paramsFromFile` cannot be used with the `%s` operator", c.Name())
+		}
+		if len(c.Operands()) == 0 {
+			return fmt.Errorf("the `%s` operator needs at least one validator", c.Name())
 		}
 	}
 	return nil
 }
 
+// Name returns the validator type or the logical operator if the validator is composed of other validators.
+func (c *ValidatorConfig) Name() string {
+	switch {
+	case c.AllOf != nil:
+		return AllOfOperator
+	case c.AnyOf != nil:
+		return AnyOfOperator
+	case c.Not != nil:
+		return NotOperator
+	}
+	return c.ValidatorType
+}
+
+// Operands returns the validators composed using the logical operator, empty if the config is not a logical operator.
+func (c *ValidatorConfig) Operands() []ValidatorConfig {
+	switch {
+	case c.AllOf != nil:
+		return c.AllOf
+	case c.AnyOf != nil:
+		return c.AnyOf
+	case c.Not != nil:
+		return []ValidatorConfig{*c.Not}
+	}
+	return nil
+}
+
+// ResolveRelativePaths sets the BaseDir and loads the paramsFromFile of the validator and all its operands.
+func (c *ValidatorConfig) ResolveRelativePaths(configDir string) error {
+	c.BaseDir = configDir
+	if err := c.loadParamsFromFile(configDir); err != nil {
+		return err
+	}
+	for _, operands := range [][]ValidatorConfig{c.AllOf, c.AnyOf} {
+		for i := range operands {
+			if err := operands[i].ResolveRelativePaths(configDir); err != nil {
+				return err
+			}
+		}
+	}
+	if c.Not != nil {
+		return c.Not.ResolveRelativePaths(configDir)
+	}
+	return nil
+}
+
+func (c *ValidatorConfig) loadParamsFromFile(configDir string) error {
+	if c.ParamsFromFile == "" {
+		return nil
+	}
+	fileData, err := os.ReadFile(path.Join(configDir, c.ParamsFromFile))
+	if err != nil {
+		return fmt.Errorf("cannot read params from file %s: %w", c.ParamsFromFile, err)
+	}
+	if err := yaml.Unmarshal(fileData, &c.Params); err != nil {
+		return err
+	}
+	if err := expandEnvNode(&c.Params); err != nil {
+		return fmt.Errorf("expanding environment variables in params file %s: %w", c.ParamsFromFile, err)
+	}
+	return nil
+}
+
 type ValidationScope string
 
 func (t *ValidationScope) UnmarshalYAML(unmarshal func(interface{}) error) error {
@@ -181,15 +564,28 @@ func LoadConfigFile(configFilePath string) (*Config, error) {
 }
 
 func LoadConfiguration(configFilePaths []string) (*Config, error) {
+	return LoadConfigurationWithJsonnet(configFilePaths, JsonnetConfig{})
+}
+
+// LoadConfigurationWithJsonnet loads the config files same as the LoadConfiguration, the jsonnetConfig is used for evaluation of the jsonnet config files
+// and takes precedence over the jsonnet settings from the config files when evaluating the jsonnet rule files.
+func LoadConfigurationWithJsonnet(configFilePaths []string, jsonnetConfig JsonnetConfig) (*Config, error) {
 	if len(configFilePaths) == 0 {
 		return nil, fmt.Errorf("required flag --config-file not provided, try --help")
 	}
-	mainConfig, err := LoadConfigFile(configFilePaths[0])
+	loaded := map[string]struct{}{}
+	mainConfig, err := loadConfigFile(configFilePaths[0], jsonnetConfig, nil, loaded)
 	if err != nil {
 		return nil, fmt.Errorf("error loading config file %s: %w", configFilePaths[0], err)
 	}
 	for _, cf := range configFilePaths[1:] {
-		validationConfig, err := LoadConfigFile(cf)
+		if absPath, err := filepath.Abs(cf); err == nil {
+			if _, ok := loaded[absPath]; ok {
+				log.Debugf("skipping config file %s, it was already loaded", cf)
+				continue
+			}
+		}
+		validationConfig, err := loadConfigFile(cf, jsonnetConfig, nil, loaded)
 		if err != nil {
 			return nil, fmt.Errorf("error loading config file %s: %w", cf, err)
 		}
@@ -202,8 +598,33 @@ func LoadConfiguration(configFilePaths []string) (*Config, error) {
 		if validationConfig.CustomDisableComment != "" {
 			mainConfig.CustomDisableComment = validationConfig.CustomDisableComment
 		}
+		mainConfig.Jsonnet = mainConfig.Jsonnet.Merge(validationConfig.Jsonnet)
+		if err := mainConfig.mergeDefinitions(validationConfig.Definitions); err != nil {
+			return nil, fmt.Errorf("error loading config file %s: %w", cf, err)
+		}
 		mainConfig.ValidationRules = append(mainConfig.ValidationRules, validationConfig.ValidationRules...)
+		mainConfig.PrometheusBackends = append(mainConfig.PrometheusBackends, validationConfig.PrometheusBackends...)
+	}
+	backendNames := map[string]struct{}{}
+	for _, backend := range mainConfig.PrometheusBackends {
+		if _, ok := backendNames[backend.Name]; ok {
+			return nil, fmt.Errorf("prometheus backend `%s` is defined multiple times", backend.Name)
+		}
+		backendNames[backend.Name] = struct{}{}
 	}
 
+	ruleSources := map[string]string{}
+	for _, rule := range mainConfig.ValidationRules {
+		if source, ok := ruleSources[rule.Name]; ok {
+			log.Warnf("validation rule %s is defined multiple times, in %s and %s", rule.Name, source, rule.Source)
+			continue
+		}
+		ruleSources[rule.Name] = rule.Source
+	}
+	if err := mainConfig.resolveTemplates(); err != nil {
+		return nil, err
+	}
+	mainConfig.Jsonnet = mainConfig.Jsonnet.Merge(jsonnetConfig)
+	mainConfig.Files = slices.Sorted(maps.Keys(loaded))
 	return mainConfig, nil
 }
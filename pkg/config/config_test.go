@@ -0,0 +1,428 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeConfigFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		p := filepath.Join(dir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(p), 0o700))
+		require.NoError(t, os.WriteFile(p, []byte(content), 0o600))
+	}
+	return dir
+}
+
+func TestLoadConfigurationInclude(t *testing.T) {
+	dir := writeConfigFiles(t, map[string]string{
+		"validation.yaml": `
+include: ["shared/base.yaml", "shared/team.yaml"]
+customExcludeAnnotation: main_exclude
+validationRules:
+  - name: main
+    scope: All rules
+    validations:
+      - type: hasLabels
+        params:
+          labels: ["severity"]
+`,
+		"shared/base.yaml": `
+customExcludeAnnotation: base_exclude
+customDisableComment: base_disable
+validationRules:
+  - name: base
+    scope: All rules
+    validations:
+      - type: hasLabels
+        params:
+          labels: ["team"]
+`,
+		"shared/team.yaml": `
+include: ["base.yaml"]
+validationRules:
+  - name: team
+    scope: All rules
+    validations:
+      - type: hasLabels
+        params:
+          labels: ["owner"]
+`,
+	})
+	cfg, err := LoadConfiguration([]string{filepath.Join(dir, "validation.yaml")})
+	require.NoError(t, err)
+
+	var names, sources []string
+	for _, r := range cfg.ValidationRules {
+		names = append(names, r.Name)
+		sources = append(sources, r.Source)
+	}
+	assert.Equal(t, []string{"base", "team", "main"}, names)
+	assert.Equal(t, []string{
+		filepath.Join(dir, "shared/base.yaml"),
+		filepath.Join(dir, "shared/team.yaml"),
+		filepath.Join(dir, "validation.yaml"),
+	}, sources)
+	assert.Equal(t, "main_exclude", cfg.CustomExcludeAnnotation)
+	assert.Equal(t, "base_disable", cfg.CustomDisableComment)
+	assert.Len(t, cfg.Files, 3)
+}
+
+func TestLoadConfigurationIncludeInvalid(t *testing.T) {
+	tests := []struct {
+		name          string
+		files         map[string]string
+		expectedError string
+	}{
+		{
+			name: "cycle",
+			files: map[string]string{
+				"validation.yaml": `include: ["a.yaml"]`,
+				"a.yaml":          `include: ["b.yaml"]`,
+				"b.yaml":          `include: ["a.yaml"]`,
+			},
+			expectedError: "config include cycle detected",
+		},
+		{
+			name: "selfInclude",
+			files: map[string]string{
+				"validation.yaml": `include: ["validation.yaml"]`,
+			},
+			expectedError: "config include cycle detected",
+		},
+		{
+			name: "missingFile",
+			files: map[string]string{
+				"validation.yaml": `include: ["missing.yaml"]`,
+			},
+			expectedError: "loading config file",
+		},
+		{
+			name: "absolutePath",
+			files: map[string]string{
+				"validation.yaml": `include: ["/etc/validation.yaml"]`,
+			},
+			expectedError: "must be a relative path",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := writeConfigFiles(t, tt.files)
+			_, err := LoadConfiguration([]string{filepath.Join(dir, "validation.yaml")})
+			assert.ErrorContains(t, err, tt.expectedError)
+		})
+	}
+}
+
+func TestLoadConfigurationTemplates(t *testing.T) {
+	dir := writeConfigFiles(t, map[string]string{
+		"validation.yaml": `
+include: ["definitions.yaml"]
+validationRules:
+  - name: standard-alert
+    abstract: true
+    scope: Alert
+    includePaths: ["teams/**"]
+    validations:
+      - definition: standard-alert-labels
+      - id: severity
+        type: labelHasAllowedValue
+        params: { label: severity, allowedValues: ["warning", "critical"] }
+  - name: team-foo
+    extends: standard-alert
+    validations:
+      - id: severity
+        type: labelHasAllowedValue
+        params: { label: severity, allowedValues: ["critical"] }
+      - type: hasAnnotations
+        params: { annotations: ["runbook_url"] }
+  - name: team-bar
+    extends: team-foo
+    excludePaths: ["teams/bar/legacy/**"]
+    onlyIf:
+      - not:
+          definition: standard-alert-labels
+`,
+		"definitions.yaml": `
+definitions:
+  standard-alert-labels:
+    - type: hasLabels
+      params: { labels: ["severity", "team"] }
+    - definition: playbook
+  playbook:
+    - type: hasAnnotations
+      params: { annotations: ["playbook"] }
+`,
+	})
+	cfg, err := LoadConfiguration([]string{filepath.Join(dir, "validation.yaml")})
+	require.NoError(t, err)
+	require.Len(t, cfg.ValidationRules, 2)
+
+	validatorTypes := func(validators []ValidatorConfig) []string {
+		var types []string
+		for _, v := range validators {
+			types = append(types, v.Name())
+		}
+		return types
+	}
+	foo := cfg.ValidationRules[0]
+	assert.Equal(t, "team-foo", foo.Name)
+	assert.Equal(t, AlertScope, foo.Scope)
+	assert.Equal(t, []string{"teams/**"}, foo.IncludePaths)
+	assert.Equal(t, []string{"hasLabels", "hasAnnotations", "labelHasAllowedValue", "hasAnnotations"}, validatorTypes(foo.Validations))
+	var severityParams struct {
+		AllowedValues []string `yaml:"allowedValues"`
+	}
+	require.NoError(t, foo.Validations[2].Params.Decode(&severityParams))
+	assert.Equal(t, []string{"critical"}, severityParams.AllowedValues)
+	assert.Equal(t, dir, foo.Validations[0].BaseDir)
+
+	bar := cfg.ValidationRules[1]
+	assert.Equal(t, "team-bar", bar.Name)
+	assert.Equal(t, []string{"teams/bar/legacy/**"}, bar.ExcludePaths)
+	assert.Equal(t, validatorTypes(foo.Validations), validatorTypes(bar.Validations))
+	require.Len(t, bar.OnlyIf, 1)
+	assert.Equal(t, []string{"hasLabels", "hasAnnotations"}, validatorTypes(bar.OnlyIf[0].Not.AllOf))
+}
+
+func TestLoadConfigurationTemplatesInvalid(t *testing.T) {
+	tests := []struct {
+		name          string
+		config        string
+		expectedError string
+	}{
+		{
+			name: "unknownDefinition",
+			config: `
+validationRules:
+  - name: foo
+    scope: Alert
+    validations:
+      - definition: missing
+`,
+			expectedError: "unknown definition `missing`",
+		},
+		{
+			name: "definitionCycle",
+			config: `
+definitions:
+  a: [{definition: b}]
+  b: [{definition: a}]
+validationRules:
+  - name: foo
+    scope: Alert
+    validations:
+      - definition: a
+`,
+			expectedError: "definition cycle detected: a -> b -> a",
+		},
+		{
+			name: "definitionWithParams",
+			config: `
+validationRules:
+  - name: foo
+    scope: Alert
+    validations:
+      - definition: a
+        params: { labels: ["foo"] }
+`,
+			expectedError: "cannot be used with the `definition`",
+		},
+		{
+			name: "unknownExtends",
+			config: `
+validationRules:
+  - name: foo
+    extends: missing
+`,
+			expectedError: "extends unknown validation rule `missing`",
+		},
+		{
+			name: "extendsCycle",
+			config: `
+validationRules:
+  - name: foo
+    extends: bar
+  - name: bar
+    extends: foo
+`,
+			expectedError: "validation rule extends cycle detected: foo -> bar -> foo",
+		},
+		{
+			name: "duplicateID",
+			config: `
+validationRules:
+  - name: foo
+    scope: Alert
+    validations:
+      - id: a
+        type: hasLabels
+        params: { labels: ["foo"] }
+  - name: bar
+    extends: foo
+    validations:
+      - id: a
+        type: hasLabels
+        params: { labels: ["bar"] }
+      - id: a
+        type: hasLabels
+        params: { labels: ["baz"] }
+`,
+			expectedError: "validator id `a` is used multiple times",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := writeConfigFiles(t, map[string]string{"validation.yaml": tt.config})
+			_, err := LoadConfiguration([]string{filepath.Join(dir, "validation.yaml")})
+			assert.ErrorContains(t, err, tt.expectedError)
+		})
+	}
+}
+
+func TestLoadConfigurationHTTPClientConfig(t *testing.T) {
+	dir := writeConfigFiles(t, map[string]string{
+		"validation.yaml": `
+prometheus:
+  url: https://mimir.example.com/prometheus
+  httpClientConfig:
+    tls_config:
+      ca_file: certs/ca.pem
+      cert_file: certs/client.pem
+      key_file: certs/client.key
+    basic_auth:
+      username: promruval
+      password_file: secrets/password
+    proxy_url: http://proxy.example.com:3128
+`,
+	})
+	cfg, err := LoadConfiguration([]string{filepath.Join(dir, "validation.yaml")})
+	require.NoError(t, err)
+	httpConfig := cfg.Prometheus.HTTPClientConfig
+	assert.Equal(t, filepath.Join(dir, "certs/ca.pem"), httpConfig.TLSConfig.CAFile)
+	assert.Equal(t, filepath.Join(dir, "certs/client.pem"), httpConfig.TLSConfig.CertFile)
+	assert.Equal(t, filepath.Join(dir, "secrets/password"), httpConfig.BasicAuth.PasswordFile)
+	assert.Equal(t, "proxy.example.com:3128", httpConfig.ProxyURL.Host)
+	assert.True(t, httpConfig.FollowRedirects)
+
+	dir = writeConfigFiles(t, map[string]string{"validation.yaml": `
+prometheus:
+  url: https://mimir.example.com/prometheus
+  bearerTokenFile: token
+  httpClientConfig:
+    basic_auth:
+      username: promruval
+`})
+	_, err = LoadConfiguration([]string{filepath.Join(dir, "validation.yaml")})
+	assert.ErrorContains(t, err, "`bearerTokenFile` cannot be used together with the authentication configured in the `httpClientConfig`")
+}
+
+func TestLoadConfigurationPrometheusBackends(t *testing.T) {
+	dir := writeConfigFiles(t, map[string]string{
+		"validation.yaml": `
+include: ["backends.yaml"]
+prometheus:
+  url: https://prometheus.example.com
+prometheusBackends:
+  - name: thanos
+    selector:
+      paths: ["thanos/**"]
+    prometheus:
+      url: https://thanos.example.com
+      bearerTokenFile: token
+`,
+		"backends.yaml": `
+prometheusBackends:
+  - name: mimir
+    selector:
+      sourceTenants: ["team-a"]
+    prometheus:
+      url: https://mimir.example.com
+      cacheFile: mimir_cache.json
+`,
+	})
+	cfg, err := LoadConfiguration([]string{filepath.Join(dir, "validation.yaml")})
+	require.NoError(t, err)
+	require.Len(t, cfg.PrometheusBackends, 2)
+	assert.Equal(t, "mimir", cfg.PrometheusBackends[0].Name)
+	assert.Equal(t, "mimir_cache.json", cfg.PrometheusBackends[0].Prometheus.CacheFile)
+	assert.Equal(t, "thanos", cfg.PrometheusBackends[1].Name)
+	assert.Equal(t, ".promruval_cache_thanos.json", cfg.PrometheusBackends[1].Prometheus.CacheFile)
+	assert.Equal(t, filepath.Join(dir, "token"), cfg.PrometheusBackends[1].Prometheus.BearerTokenFile)
+
+	tests := []struct {
+		name          string
+		config        string
+		expectedError string
+	}{
+		{name: "missingName", config: `prometheusBackends: [{prometheus: {url: http://foo}}]`, expectedError: "prometheus backend must have a name"},
+		{name: "missingURL", config: `prometheusBackends: [{name: foo}]`, expectedError: "prometheus backend `foo` must have the `prometheus.url` set"},
+		{name: "duplicateName", config: `prometheusBackends: [{name: foo, prometheus: {url: http://foo}}, {name: foo, prometheus: {url: http://bar}}]`, expectedError: "prometheus backend `foo` is defined multiple times"},
+		{name: "invalidPath", config: `prometheusBackends: [{name: foo, selector: {paths: ["[a"]}, prometheus: {url: http://foo}}]`, expectedError: "invalid path pattern `[a`"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := writeConfigFiles(t, map[string]string{"validation.yaml": tt.config})
+			_, err := LoadConfiguration([]string{filepath.Join(dir, "validation.yaml")})
+			assert.ErrorContains(t, err, tt.expectedError)
+		})
+	}
+}
+
+func TestLoadConfigurationPrometheusRetries(t *testing.T) {
+	dir := writeConfigFiles(t, map[string]string{"validation.yaml": `prometheus: {url: http://foo}`})
+	cfg, err := LoadConfiguration([]string{filepath.Join(dir, "validation.yaml")})
+	require.NoError(t, err)
+	assert.Equal(t, 3, cfg.Prometheus.MaxRetries)
+	assert.Equal(t, 500*time.Millisecond, cfg.Prometheus.RetryMinBackoff)
+	assert.Equal(t, 10*time.Second, cfg.Prometheus.RetryMaxBackoff)
+	assert.Zero(t, cfg.Prometheus.MaxQPS)
+
+	tests := []struct {
+		name          string
+		config        string
+		expectedError string
+	}{
+		{name: "negativeRetries", config: `prometheus: {url: http://foo, maxRetries: -1}`, expectedError: "`maxRetries` and `maxQps` cannot be negative"},
+		{name: "negativeQPS", config: `prometheus: {url: http://foo, maxQps: -1}`, expectedError: "`maxRetries` and `maxQps` cannot be negative"},
+		{name: "minBackoffGreaterThanMax", config: `prometheus: {url: http://foo, retryMinBackoff: 1m, retryMaxBackoff: 1s}`, expectedError: "`retryMinBackoff` must be positive and not greater than `retryMaxBackoff`"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := writeConfigFiles(t, map[string]string{"validation.yaml": tt.config})
+			_, err := LoadConfiguration([]string{filepath.Join(dir, "validation.yaml")})
+			assert.ErrorContains(t, err, tt.expectedError)
+		})
+	}
+}
+
+func TestLoadConfigurationPrometheusMode(t *testing.T) {
+	dir := writeConfigFiles(t, map[string]string{"validation.yaml": `prometheus: {url: http://foo, mode: replay, fixturesDir: fixtures}`})
+	cfg, err := LoadConfiguration([]string{filepath.Join(dir, "validation.yaml")})
+	require.NoError(t, err)
+	assert.Equal(t, PrometheusModeReplay, cfg.Prometheus.Mode)
+	assert.Equal(t, filepath.Join(dir, "fixtures"), cfg.Prometheus.FixturesDir)
+
+	tests := []struct {
+		name          string
+		config        string
+		expectedError string
+	}{
+		{name: "unknownMode", config: `prometheus: {url: http://foo, mode: foo}`, expectedError: "invalid `mode` foo, must be one of: record, replay"},
+		{name: "missingFixturesDir", config: `prometheus: {url: http://foo, mode: record}`, expectedError: "`fixturesDir` must be set in the `record` mode"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := writeConfigFiles(t, map[string]string{"validation.yaml": tt.config})
+			_, err := LoadConfiguration([]string{filepath.Join(dir, "validation.yaml")})
+			assert.ErrorContains(t, err, tt.expectedError)
+		})
+	}
+}
@@ -0,0 +1,120 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// envVarRegexp matches the `${VAR}`, `${VAR:-default}`, `${VAR-default}`, `${VAR:?message}` and `${VAR?message}` references,
+// the `$${` is an escaped `${`. Only valid variable names are matched, so PromQL replacement references like `${1}` are kept as they are.
+var envVarRegexp = regexp.MustCompile(`\$\$\{|\$\{([A-Za-z_][A-Za-z0-9_]*)(?:(:?[-?])([^}]*))?\}`)
+
+// expandEnv replaces references to the environment variables in the string using the shell-like syntax.
+// Unset variable without a default fails, so the literal `${...}` in existing configs does not silently change its meaning.
+func expandEnv(s string) (string, error) {
+	var err error
+	expanded := envVarRegexp.ReplaceAllStringFunc(s, func(match string) string {
+		if match == "$${" {
+			return "${"
+		}
+		groups := envVarRegexp.FindStringSubmatch(match)
+		name, operator, arg := groups[1], groups[2], groups[3]
+		value, isSet := os.LookupEnv(name)
+		// With the colon, the empty variable is handled the same way as the unset one.
+		if strings.HasPrefix(operator, ":") && value == "" {
+			isSet = false
+		}
+		if isSet {
+			return value
+		}
+		switch strings.TrimPrefix(operator, ":") {
+		case "-":
+			return arg
+		case "?":
+			if err == nil {
+				if arg == "" {
+					arg = "not set"
+				}
+				err = fmt.Errorf("required environment variable %s: %s", name, arg)
+			}
+			return ""
+		}
+		if err == nil {
+			err = fmt.Errorf("environment variable %s is not set, use `${%s:-}` to default to an empty string or `$${` for a literal `${`", name, name)
+		}
+		return ""
+	})
+	return expanded, err
+}
+
+// expandEnvNode expands the environment variables in all the scalar values of the yaml node, mapping keys are kept as they are.
+func expandEnvNode(node *yaml.Node) error {
+	switch node.Kind {
+	case yaml.ScalarNode:
+		expanded, err := expandEnv(node.Value)
+		if err != nil {
+			return fmt.Errorf("line %d: %w", node.Line, err)
+		}
+		if expanded != node.Value && node.Style&(yaml.SingleQuotedStyle|yaml.DoubleQuotedStyle|yaml.TaggedStyle) == 0 {
+			// Let the implicit type of the unquoted value be resolved from the expanded value, so for example booleans can be set using the variables.
+			node.Tag = ""
+		}
+		node.Value = expanded
+	case yaml.MappingNode:
+		for i := 1; i < len(node.Content); i += 2 {
+			if err := expandEnvNode(node.Content[i]); err != nil {
+				return err
+			}
+		}
+	case yaml.DocumentNode, yaml.SequenceNode:
+		for _, n := range node.Content {
+			if err := expandEnvNode(n); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+var errorLineRegexp = regexp.MustCompile(`^line (\d+):`)
+
+// restoreErrorLines replaces the line numbers in the errors of decoding the encoded node by the lines of the node in the original file.
+func restoreErrorLines(err error, encoded []byte, original *yaml.Node) error {
+	var typeErr *yaml.TypeError
+	if !errors.As(err, &typeErr) {
+		return err
+	}
+	var encodedNode yaml.Node
+	if yaml.Unmarshal(encoded, &encodedNode) != nil {
+		return err
+	}
+	lines := map[int]int{}
+	mapNodeLines(&encodedNode, original, lines)
+	restored := &yaml.TypeError{Errors: make([]string, len(typeErr.Errors))}
+	for i, e := range typeErr.Errors {
+		restored.Errors[i] = errorLineRegexp.ReplaceAllStringFunc(e, func(match string) string {
+			line, _ := strconv.Atoi(errorLineRegexp.FindStringSubmatch(match)[1])
+			if originalLine, ok := lines[line]; ok {
+				return fmt.Sprintf("line %d:", originalLine)
+			}
+			return match
+		})
+	}
+	return restored
+}
+
+// mapNodeLines maps the lines of the encoded node to the lines of the original node it was encoded from, they have the same structure.
+func mapNodeLines(encoded, original *yaml.Node, lines map[int]int) {
+	if _, ok := lines[encoded.Line]; !ok {
+		lines[encoded.Line] = original.Line
+	}
+	for i := range min(len(encoded.Content), len(original.Content)) {
+		mapNodeLines(encoded.Content[i], original.Content[i], lines)
+	}
+}
@@ -0,0 +1,150 @@
+package config
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+func TestExpandEnv(t *testing.T) {
+	t.Setenv("PROMRUVAL_TEST_SET", "foo")
+	t.Setenv("PROMRUVAL_TEST_EMPTY", "")
+	tests := []struct {
+		name          string
+		input         string
+		expected      string
+		expectedError string
+	}{
+		{name: "noVariables", input: "foo", expected: "foo"},
+		{name: "set", input: "https://${PROMRUVAL_TEST_SET}.example.com", expected: "https://foo.example.com"},
+		{name: "unset", input: "${PROMRUVAL_TEST_UNSET}", expectedError: "environment variable PROMRUVAL_TEST_UNSET is not set"},
+		{name: "emptyDefaultUnset", input: "${PROMRUVAL_TEST_UNSET:-}", expected: ""},
+		{name: "defaultUnset", input: "${PROMRUVAL_TEST_UNSET:-bar}", expected: "bar"},
+		{name: "defaultEmpty", input: "${PROMRUVAL_TEST_EMPTY:-bar}", expected: "bar"},
+		{name: "defaultWithoutColonEmpty", input: "${PROMRUVAL_TEST_EMPTY-bar}", expected: ""},
+		{name: "defaultSet", input: "${PROMRUVAL_TEST_SET:-bar}", expected: "foo"},
+		{name: "requiredSet", input: "${PROMRUVAL_TEST_SET:?}", expected: "foo"},
+		{name: "requiredUnset", input: "${PROMRUVAL_TEST_UNSET:?tenant must be set}", expectedError: "required environment variable PROMRUVAL_TEST_UNSET: tenant must be set"},
+		{name: "requiredEmpty", input: "${PROMRUVAL_TEST_EMPTY:?}", expectedError: "required environment variable PROMRUVAL_TEST_EMPTY: not set"},
+		{name: "requiredWithoutColonEmpty", input: "${PROMRUVAL_TEST_EMPTY?}", expected: ""},
+		{name: "escaped", input: "$${PROMRUVAL_TEST_SET}", expected: "${PROMRUVAL_TEST_SET}"},
+		{name: "promqlReplacement", input: `label_replace(up, "foo", "${1}", "bar", "(.*)")`, expected: `label_replace(up, "foo", "${1}", "bar", "(.*)")`},
+		{name: "template", input: "{{ $labels.instance }} $value", expected: "{{ $labels.instance }} $value"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expanded, err := expandEnv(tt.input)
+			if tt.expectedError != "" {
+				assert.ErrorContains(t, err, tt.expectedError)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, expanded)
+		})
+	}
+}
+
+func TestExpandEnvNodeTags(t *testing.T) {
+	t.Setenv("PROMRUVAL_TEST_NUMBER", "1")
+	var node yaml.Node
+	require.NoError(t, yaml.Unmarshal([]byte("implicit: ${PROMRUVAL_TEST_NUMBER}\nexplicit: !!str ${PROMRUVAL_TEST_NUMBER}\nquoted: \"${PROMRUVAL_TEST_NUMBER}\"\n"), &node))
+	require.NoError(t, expandEnvNode(&node))
+	mapping := node.Content[0]
+	assert.Equal(t, "!!int", mapping.Content[1].ShortTag())
+	assert.Equal(t, "!!str", mapping.Content[3].ShortTag())
+	assert.Equal(t, "!!str", mapping.Content[5].ShortTag())
+}
+
+func TestLoadConfigurationEnv(t *testing.T) {
+	t.Setenv("PROMRUVAL_TEST_URL", "https://prometheus.example.com")
+	t.Setenv("PROMRUVAL_TEST_SKIP_TLS", "true")
+	t.Setenv("PROMRUVAL_TEST_LABEL", "team")
+	dir := writeConfigFiles(t, map[string]string{
+		"validation.yaml": `
+prometheus:
+  url: ${PROMRUVAL_TEST_URL}
+  insecureSkipTlsVerify: ${PROMRUVAL_TEST_SKIP_TLS}
+  httpHeaders:
+    X-Scope-OrgID: ${PROMRUVAL_TEST_TENANT:-default}
+  httpHeadersFile:
+    X-Custom: secrets/custom.txt
+validationRules:
+  - name: foo
+    scope: All rules
+    validations:
+      - type: hasLabels
+        params:
+          labels: ["${PROMRUVAL_TEST_LABEL}"]
+`,
+		"secrets/custom.txt": "foo",
+	})
+	cfg, err := LoadConfiguration([]string{filepath.Join(dir, "validation.yaml")})
+	require.NoError(t, err)
+	assert.Equal(t, "https://prometheus.example.com", cfg.Prometheus.URL)
+	assert.True(t, cfg.Prometheus.InsecureSkipTLSVerify)
+	assert.Equal(t, map[string]string{"X-Scope-OrgID": "default"}, cfg.Prometheus.HTTPHeaders)
+	assert.Equal(t, map[string]string{"X-Custom": filepath.Join(dir, "secrets/custom.txt")}, cfg.Prometheus.HTTPHeadersFile)
+	var params struct {
+		Labels []string `yaml:"labels"`
+	}
+	require.NoError(t, cfg.ValidationRules[0].Validations[0].Params.Decode(&params))
+	assert.Equal(t, []string{"team"}, params.Labels)
+
+	dir = writeConfigFiles(t, map[string]string{"validation.yaml": `
+prometheus:
+  url: ${PROMRUVAL_TEST_UNSET:?the Prometheus URL is required}
+`})
+	_, err = LoadConfiguration([]string{filepath.Join(dir, "validation.yaml")})
+	assert.ErrorContains(t, err, "line 3: required environment variable PROMRUVAL_TEST_UNSET: the Prometheus URL is required")
+}
+
+func TestLoadConfigurationEnvErrorPositions(t *testing.T) {
+	t.Setenv("PROMRUVAL_TEST_TIMEOUT", "foo")
+	tests := []struct {
+		name          string
+		config        string
+		expectedError string
+	}{
+		{name: "invalidExpandedValue", config: `
+# The comments and blank lines are kept in the positions.
+
+prometheus:
+  url: https://prometheus.example.com
+  timeout: ${PROMRUVAL_TEST_TIMEOUT}
+`, expectedError: "line 6: cannot unmarshal !!str `foo` into time.Duration"},
+		{name: "unknownField", config: `
+# The comments and blank lines are kept in the positions.
+
+prometheus:
+  url: ${PROMRUVAL_TEST_URL:-https://prometheus.example.com}
+  timout: 1m
+`, expectedError: "line 6: field timout not found in type config.plain"},
+		{name: "unknownNestedField", config: `
+prometheus:
+  url: https://prometheus.example.com
+  httpClientConfig:
+    follow_redirects: true
+    proxy_url: http://proxy.example.com
+    bearer: foo
+`, expectedError: "line 7: field bearer not found in type config.plain"},
+		{name: "unknownValidatorField", config: `
+validationRules:
+  - name: foo
+    scope: All rules
+    validations:
+      - type: hasLabels
+        parms:
+          labels: [team]
+`, expectedError: "line 7: field parms not found in type config.plain"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := writeConfigFiles(t, map[string]string{"validation.yaml": tt.config})
+			_, err := LoadConfiguration([]string{filepath.Join(dir, "validation.yaml")})
+			assert.ErrorContains(t, err, tt.expectedError)
+		})
+	}
+}
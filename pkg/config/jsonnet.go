@@ -0,0 +1,96 @@
+package config
+
+import (
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/google/go-jsonnet"
+)
+
+const (
+	jsonnetBundlerFile      = "jsonnetfile.json"
+	jsonnetBundlerVendorDir = "vendor"
+)
+
+// JsonnetConfig configures the VM evaluating the jsonnet config and rule files.
+type JsonnetConfig struct {
+	// ExtStr and ExtCode are external variables available using the std.extVar.
+	ExtStr  map[string]string `yaml:"extStr,omitempty"`
+	ExtCode map[string]string `yaml:"extCode,omitempty"`
+	// TLAStr and TLACode are the top-level arguments passed to the file if it evaluates to a function.
+	TLAStr  map[string]string `yaml:"tlaStr,omitempty"`
+	TLACode map[string]string `yaml:"tlaCode,omitempty"`
+	// JPath are library search directories, the rightmost wins. In the config file, they are relative to the config file.
+	JPath []string `yaml:"jpath,omitempty"`
+}
+
+func mergeMaps(base, override map[string]string) map[string]string {
+	if len(base) == 0 && len(override) == 0 {
+		return nil
+	}
+	merged := maps.Clone(base)
+	if merged == nil {
+		merged = map[string]string{}
+	}
+	maps.Copy(merged, override)
+	return merged
+}
+
+// Merge returns the config combined with the other one, values of the other config take precedence.
+func (c JsonnetConfig) Merge(other JsonnetConfig) JsonnetConfig {
+	return JsonnetConfig{
+		ExtStr:  mergeMaps(c.ExtStr, other.ExtStr),
+		ExtCode: mergeMaps(c.ExtCode, other.ExtCode),
+		TLAStr:  mergeMaps(c.TLAStr, other.TLAStr),
+		TLACode: mergeMaps(c.TLACode, other.TLACode),
+		JPath:   slices.Concat(c.JPath, other.JPath),
+	}
+}
+
+// NewVM creates the VM to evaluate the given file. If the file is part of a jsonnet-bundler project,
+// the vendor directory of the closest project is added as a library search directory with the lowest priority.
+func (c JsonnetConfig) NewVM(fileName string) *jsonnet.VM {
+	vm := jsonnet.MakeVM()
+	for k, v := range c.ExtStr {
+		vm.ExtVar(k, v)
+	}
+	for k, v := range c.ExtCode {
+		vm.ExtCode(k, v)
+	}
+	for k, v := range c.TLAStr {
+		vm.TLAVar(k, v)
+	}
+	for k, v := range c.TLACode {
+		vm.TLACode(k, v)
+	}
+	jpath := slices.Clone(c.JPath)
+	if vendorDir := jsonnetBundlerVendor(fileName); vendorDir != "" {
+		jpath = append([]string{vendorDir}, jpath...)
+	}
+	vm.Importer(&jsonnet.FileImporter{JPaths: jpath})
+	return vm
+}
+
+// jsonnetBundlerVendor returns the vendor directory of the closest parent directory containing the jsonnetfile.json.
+func jsonnetBundlerVendor(fileName string) string {
+	dir, err := filepath.Abs(filepath.Dir(fileName))
+	if err != nil {
+		return ""
+	}
+	for {
+		if _, err := os.Stat(filepath.Join(dir, jsonnetBundlerFile)); err == nil {
+			vendorDir := filepath.Join(dir, jsonnetBundlerVendorDir)
+			if info, err := os.Stat(vendorDir); err == nil && info.IsDir() {
+				return vendorDir
+			}
+			return ""
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
@@ -0,0 +1,72 @@
+package config
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJsonnetConfigNewVM(t *testing.T) {
+	dir := writeConfigFiles(t, map[string]string{
+		"project/jsonnetfile.json":                            `{}`,
+		"project/vendor/github.com/foo/mixin/mixin.libsonnet": `{ source: "vendor" }`,
+		"project/lib/github.com/foo/mixin/mixin.libsonnet":    `{ source: "jpath" }`,
+		"project/rules/rules.jsonnet": `
+local mixin = import 'github.com/foo/mixin/mixin.libsonnet';
+function(threshold) { source: mixin.source, cluster: std.extVar('cluster'), replicas: std.extVar('replicas'), threshold: threshold }
+`,
+	})
+	rulesFile := filepath.Join(dir, "project/rules/rules.jsonnet")
+	tests := []struct {
+		name     string
+		config   JsonnetConfig
+		expected string
+	}{
+		{
+			name:     "vendor",
+			config:   JsonnetConfig{ExtStr: map[string]string{"cluster": "prod"}, ExtCode: map[string]string{"replicas": "1+1"}, TLACode: map[string]string{"threshold": "0.5"}},
+			expected: `{"cluster": "prod", "replicas": 2, "source": "vendor", "threshold": 0.5}`,
+		},
+		{
+			name: "jpathOverridesVendor",
+			config: JsonnetConfig{
+				ExtStr:  map[string]string{"cluster": "dev"},
+				ExtCode: map[string]string{"replicas": "1"},
+				TLAStr:  map[string]string{"threshold": "high"},
+				JPath:   []string{filepath.Join(dir, "project/lib")},
+			},
+			expected: `{"cluster": "dev", "replicas": 1, "source": "jpath", "threshold": "high"}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			output, err := tt.config.NewVM(rulesFile).EvaluateFile(rulesFile)
+			require.NoError(t, err)
+			assert.JSONEq(t, tt.expected, output)
+		})
+	}
+}
+
+func TestJsonnetConfigMerge(t *testing.T) {
+	base := JsonnetConfig{ExtStr: map[string]string{"cluster": "dev", "env": "dev"}, JPath: []string{"a"}}
+	override := JsonnetConfig{ExtStr: map[string]string{"cluster": "prod"}, JPath: []string{"b"}}
+	assert.Equal(t, JsonnetConfig{ExtStr: map[string]string{"cluster": "prod", "env": "dev"}, JPath: []string{"a", "b"}}, base.Merge(override))
+}
+
+func TestLoadConfigurationWithJsonnet(t *testing.T) {
+	dir := writeConfigFiles(t, map[string]string{
+		"validation.jsonnet": `
+{
+  jsonnet: { extStr: { cluster: 'config', env: 'config' }, jpath: ['lib'] },
+  validationRules: [{ name: std.extVar('cluster'), scope: 'All rules', validations: [{ type: 'hasLabels', params: { labels: ['severity'] } }] }],
+}
+`,
+	})
+	cfg, err := LoadConfigurationWithJsonnet([]string{filepath.Join(dir, "validation.jsonnet")}, JsonnetConfig{ExtStr: map[string]string{"cluster": "flag"}})
+	require.NoError(t, err)
+	assert.Equal(t, "flag", cfg.ValidationRules[0].Name)
+	assert.Equal(t, map[string]string{"cluster": "flag", "env": "config"}, cfg.Jsonnet.ExtStr)
+	assert.Equal(t, []string{filepath.Join(dir, "lib")}, cfg.Jsonnet.JPath)
+}
@@ -0,0 +1,146 @@
+package config
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// resolveTemplates expands the definitions referenced by the validators, merges the validation rules with the ones they extend
+// and drops the abstract rules, which serve only as templates for the other rules.
+func (c *Config) resolveTemplates() error {
+	for i := range c.ValidationRules {
+		rule := &c.ValidationRules[i]
+		var err error
+		if rule.OnlyIf, err = c.expandDefinitions(rule.OnlyIf, nil); err != nil {
+			return fmt.Errorf("validation rule `%s`: %w", rule.Name, err)
+		}
+		if rule.Validations, err = c.expandDefinitions(rule.Validations, nil); err != nil {
+			return fmt.Errorf("validation rule `%s`: %w", rule.Name, err)
+		}
+	}
+
+	rulesByName := map[string]ValidationRule{}
+	for _, rule := range c.ValidationRules {
+		if _, ok := rulesByName[rule.Name]; !ok {
+			rulesByName[rule.Name] = rule
+		}
+	}
+	var rules []ValidationRule
+	for _, rule := range c.ValidationRules {
+		resolvedRule, err := resolveExtends(rule, rulesByName, nil)
+		if err != nil {
+			return err
+		}
+		if !resolvedRule.Abstract {
+			rules = append(rules, resolvedRule)
+		}
+	}
+	c.ValidationRules = rules
+	return nil
+}
+
+// expandDefinitions replaces the validators referencing a definition with the validators of the definition, including the nested operands.
+func (c *Config) expandDefinitions(validators []ValidatorConfig, definitionStack []string) ([]ValidatorConfig, error) {
+	var expanded []ValidatorConfig
+	for _, v := range validators {
+		if v.Definition == "" {
+			var err error
+			if v.AllOf != nil {
+				if v.AllOf, err = c.expandDefinitions(v.AllOf, definitionStack); err != nil {
+					return nil, err
+				}
+			}
+			if v.AnyOf != nil {
+				if v.AnyOf, err = c.expandDefinitions(v.AnyOf, definitionStack); err != nil {
+					return nil, err
+				}
+			}
+			if v.Not != nil {
+				operands, err := c.expandDefinitions([]ValidatorConfig{*v.Not}, definitionStack)
+				if err != nil {
+					return nil, err
+				}
+				// The not operator has a single operand, definition with multiple validators needs to be composed using allOf.
+				if len(operands) == 1 {
+					v.Not = &operands[0]
+				} else {
+					v.Not = &ValidatorConfig{AllOf: operands}
+				}
+			}
+			expanded = append(expanded, v)
+			continue
+		}
+		if slices.Contains(definitionStack, v.Definition) {
+			return nil, fmt.Errorf("definition cycle detected: %s -> %s", strings.Join(definitionStack, " -> "), v.Definition)
+		}
+		definition, ok := c.Definitions[v.Definition]
+		if !ok {
+			return nil, fmt.Errorf("unknown definition `%s`", v.Definition)
+		}
+		definitionValidators, err := c.expandDefinitions(definition, append(slices.Clone(definitionStack), v.Definition))
+		if err != nil {
+			return nil, err
+		}
+		expanded = append(expanded, definitionValidators...)
+	}
+	return expanded, nil
+}
+
+// resolveExtends merges the rule with the rule it extends, extendsStack is used to detect cycles.
+func resolveExtends(rule ValidationRule, rulesByName map[string]ValidationRule, extendsStack []string) (ValidationRule, error) {
+	if rule.Extends == "" {
+		return rule, nil
+	}
+	extendsStack = append(extendsStack, rule.Name)
+	if slices.Contains(extendsStack, rule.Extends) {
+		return rule, fmt.Errorf("validation rule extends cycle detected: %s -> %s", strings.Join(extendsStack, " -> "), rule.Extends)
+	}
+	parent, ok := rulesByName[rule.Extends]
+	if !ok {
+		return rule, fmt.Errorf("validation rule `%s` (defined in %s) extends unknown validation rule `%s`", rule.Name, rule.Source, rule.Extends)
+	}
+	parent, err := resolveExtends(parent, rulesByName, extendsStack)
+	if err != nil {
+		return rule, err
+	}
+	if rule.Scope == "" {
+		rule.Scope = parent.Scope
+	}
+	if rule.OnlyIf, err = mergeValidators(parent.OnlyIf, rule.OnlyIf); err != nil {
+		return rule, fmt.Errorf("validation rule `%s`: %w", rule.Name, err)
+	}
+	if rule.Validations, err = mergeValidators(parent.Validations, rule.Validations); err != nil {
+		return rule, fmt.Errorf("validation rule `%s`: %w", rule.Name, err)
+	}
+	if rule.IncludePaths == nil {
+		rule.IncludePaths = parent.IncludePaths
+	}
+	if rule.ExcludePaths == nil {
+		rule.ExcludePaths = parent.ExcludePaths
+	}
+	return rule, nil
+}
+
+// mergeValidators appends the validators to the inherited ones, validators with an id replace the inherited validator with the same id.
+func mergeValidators(inherited, validators []ValidatorConfig) ([]ValidatorConfig, error) {
+	merged := slices.Clone(inherited)
+	ids := map[string]struct{}{}
+	for _, v := range validators {
+		if v.ID == "" {
+			merged = append(merged, v)
+			continue
+		}
+		if _, ok := ids[v.ID]; ok {
+			return nil, fmt.Errorf("validator id `%s` is used multiple times", v.ID)
+		}
+		ids[v.ID] = struct{}{}
+		i := slices.IndexFunc(merged, func(m ValidatorConfig) bool { return m.ID == v.ID })
+		if i < 0 {
+			merged = append(merged, v)
+			continue
+		}
+		merged[i] = v
+	}
+	return merged, nil
+}
@@ -19,12 +19,26 @@ func ValidatorFromConfig(scope config.ValidationScope, validatorType string, val
 	return newValidator, nil
 }
 
-func ValidationRulesFromConfig(validationConfig *config.Config, disabledRules, enabledRules []string) ([]*validationrule.ValidationRule, error) {
+// ruleDescription returns the rule name with the config file it was loaded from, if known.
+func ruleDescription(validationRule config.ValidationRule) string {
+	if validationRule.Source == "" {
+		return fmt.Sprintf("`%s`", validationRule.Name)
+	}
+	return fmt.Sprintf("`%s` (defined in %s)", validationRule.Name, validationRule.Source)
+}
+
+func ValidationRulesFromConfig(validationConfig *config.Config, disabledRules, enabledRules []string) (_ []*validationrule.ValidationRule, err error) {
 	var validationRules []*validationrule.ValidationRule
+	defer func() {
+		// Release the validators loaded before the error, e.g. the WASM plugins.
+		if err != nil {
+			_ = validationrule.CloseAll(validationRules)
+		}
+	}()
 rulesIteration:
 	for _, validationRule := range validationConfig.ValidationRules {
 		if validationRule.Scope == "" {
-			return nil, fmt.Errorf("scope is missing in the validation rule `%s`", validationRule.Name)
+			return nil, fmt.Errorf("scope is missing in the validation rule %s", ruleDescription(validationRule))
 		}
 		for _, disabledRule := range disabledRules {
 			if disabledRule == validationRule.Name {
@@ -37,28 +51,30 @@ rulesIteration:
 			}
 		}
 		newRule := validationrule.New(validationRule.Name, validationRule.Scope)
+		newRule.SetPaths(validationRule.IncludePaths, validationRule.ExcludePaths)
+		newRule.SetSource(validationRule.Source)
+		validationRules = append(validationRules, newRule)
 		for _, validatorConfig := range validationRule.OnlyIf {
 			// Do not limit the scope of onlyIf validators, will be applied only to the entities where possible
-			v, err := ValidatorFromConfig(config.AllScope, validatorConfig.ValidatorType, validatorConfig)
+			v, err := ValidatorFromConfig(config.AllScope, validatorConfig.Name(), validatorConfig)
 			if err != nil {
-				return nil, fmt.Errorf("loading config for onlyIf validator in the `%s` rule: %w", validationRule.Name, err)
+				return nil, fmt.Errorf("loading config for onlyIf validator in the %s rule: %w", ruleDescription(validationRule), err)
 			}
 			if v == nil {
 				continue
 			}
-			newRule.AddOnlyIfValidator(v, validatorConfig.AdditionalDetails)
+			newRule.AddNamedOnlyIfValidator(validatorConfig.Name(), v, validatorConfig.AdditionalDetails)
 		}
 		for _, validatorConfig := range validationRule.Validations {
-			v, err := ValidatorFromConfig(validationRule.Scope, validatorConfig.ValidatorType, validatorConfig)
+			v, err := ValidatorFromConfig(validationRule.Scope, validatorConfig.Name(), validatorConfig)
 			if err != nil {
-				return nil, fmt.Errorf("loading config for validator in the `%s` rule: %w", validationRule.Name, err)
+				return nil, fmt.Errorf("loading config for validator in the %s rule: %w", ruleDescription(validationRule), err)
 			}
 			if v == nil {
 				continue
 			}
-			newRule.AddValidator(v, validatorConfig.AdditionalDetails)
+			newRule.AddNamedValidator(validatorConfig.Name(), v, validatorConfig.AdditionalDetails)
 		}
-		validationRules = append(validationRules, newRule)
 	}
 	return validationRules, nil
 }
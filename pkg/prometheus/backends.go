@@ -0,0 +1,105 @@
+package prometheus
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+
+	"github.com/bmatcuk/doublestar/v4"
+	"github.com/fusakla/promruval/v3/pkg/config"
+	"github.com/fusakla/promruval/v3/pkg/unmarshaler"
+	"github.com/prometheus/prometheus/model/rulefmt"
+	log "github.com/sirupsen/logrus"
+)
+
+type backend struct {
+	config.PrometheusBackend
+	client *Client
+}
+
+// Backends selects the Prometheus client used by the validators for each group and rule.
+type Backends struct {
+	// Default client is used if none of the named backends matches, it can be nil if there is no default Prometheus configured.
+	Default  *Client
+	backends []backend
+}
+
+// NewBackends creates clients of the named backends, the first backend with the matching selector is used, otherwise the default client.
+func NewBackends(defaultClient *Client, backendConfigs []config.PrometheusBackend) (*Backends, error) {
+	b := &Backends{Default: defaultClient}
+	for _, backendConfig := range backendConfigs {
+		client, err := NewClient(backendConfig.Prometheus)
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize client of the prometheus backend %s: %w", backendConfig.Name, err)
+		}
+		b.backends = append(b.backends, backend{PrometheusBackend: backendConfig, client: client})
+	}
+	return b, nil
+}
+
+// ClientFor returns the client for the group in the given file, rule is nil for the group level validations.
+func (b *Backends) ClientFor(fileName string, group unmarshaler.RuleGroup, rule *rulefmt.Rule) *Client {
+	if b == nil {
+		return nil
+	}
+	for _, backend := range b.backends {
+		if selectorMatches(backend.Selector, fileName, group, rule) {
+			log.Debugf("using prometheus backend %s for file %s group %s", backend.Name, fileName, group.Name)
+			return backend.client
+		}
+	}
+	return b.Default
+}
+
+// DumpCache persists the cache of the named backends, the default client is managed by its creator.
+func (b *Backends) DumpCache() {
+	if b == nil {
+		return
+	}
+	for _, backend := range b.backends {
+		backend.client.DumpCache()
+	}
+}
+
+func selectorMatches(selector config.PrometheusBackendSelector, fileName string, group unmarshaler.RuleGroup, rule *rulefmt.Rule) bool {
+	if len(selector.Paths) > 0 {
+		normalizedPath := filepath.ToSlash(filepath.Clean(fileName))
+		if !slices.ContainsFunc(selector.Paths, func(pattern string) bool { return doublestar.MatchUnvalidated(pattern, normalizedPath) }) {
+			return false
+		}
+	}
+	for name, value := range selector.GroupLabels {
+		if v, ok := group.Labels[name]; !ok || v != value {
+			return false
+		}
+	}
+	if len(selector.SourceTenants) > 0 && !slices.ContainsFunc(selector.SourceTenants, func(tenant string) bool { return slices.Contains(group.SourceTenants, tenant) }) {
+		return false
+	}
+	if len(selector.Annotations) > 0 {
+		if rule == nil {
+			return false
+		}
+		for name, value := range selector.Annotations {
+			if v, ok := rule.Annotations[name]; !ok || v != value {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// Stats returns the sum of the cumulative request counters of all the clients.
+func (b *Backends) Stats() Stats {
+	var stats Stats
+	if b == nil {
+		return stats
+	}
+	if b.Default != nil {
+		stats = stats.Add(b.Default.Stats())
+	}
+	for _, backend := range b.backends {
+		stats = stats.Add(backend.client.Stats())
+	}
+	return stats
+}
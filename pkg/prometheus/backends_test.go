@@ -0,0 +1,51 @@
+package prometheus
+
+import (
+	"testing"
+
+	"github.com/fusakla/promruval/v3/pkg/config"
+	"github.com/fusakla/promruval/v3/pkg/unmarshaler"
+	"github.com/prometheus/prometheus/model/rulefmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBackendsClientFor(t *testing.T) {
+	backendConfig := func(name string, selector config.PrometheusBackendSelector) config.PrometheusBackend {
+		return config.PrometheusBackend{Name: name, Selector: selector, Prometheus: config.PrometheusConfig{URL: "http://" + name, DisableCache: true}}
+	}
+	defaultClient, err := NewClient(config.PrometheusConfig{URL: "http://default", DisableCache: true})
+	require.NoError(t, err)
+	backends, err := NewBackends(defaultClient, []config.PrometheusBackend{
+		backendConfig("thanos", config.PrometheusBackendSelector{Paths: []string{"thanos/**"}}),
+		backendConfig("prod", config.PrometheusBackendSelector{GroupLabels: map[string]string{"cluster": "prod"}}),
+		backendConfig("mimir", config.PrometheusBackendSelector{SourceTenants: []string{"team-a", "team-b"}}),
+		backendConfig("annotated", config.PrometheusBackendSelector{Annotations: map[string]string{"backend": "annotated"}}),
+	})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name        string
+		fileName    string
+		group       unmarshaler.RuleGroup
+		rule        *rulefmt.Rule
+		expectedURL string
+	}{
+		{name: "default", fileName: "rules/foo.yaml", expectedURL: "http://default"},
+		{name: "path", fileName: "./thanos/foo/rules.yaml", expectedURL: "http://thanos"},
+		{name: "groupLabel", fileName: "rules/foo.yaml", group: unmarshaler.RuleGroup{Labels: map[string]string{"cluster": "prod"}}, expectedURL: "http://prod"},
+		{name: "groupLabelOtherValue", fileName: "rules/foo.yaml", group: unmarshaler.RuleGroup{Labels: map[string]string{"cluster": "dev"}}, expectedURL: "http://default"},
+		{name: "sourceTenants", fileName: "rules/foo.yaml", group: unmarshaler.RuleGroup{SourceTenants: []string{"team-b"}}, expectedURL: "http://mimir"},
+		{name: "annotation", fileName: "rules/foo.yaml", rule: &rulefmt.Rule{Annotations: map[string]string{"backend": "annotated"}}, expectedURL: "http://annotated"},
+		{name: "annotationGroupLevel", fileName: "rules/foo.yaml", expectedURL: "http://default"},
+		{name: "firstMatchWins", fileName: "thanos/rules.yaml", group: unmarshaler.RuleGroup{Labels: map[string]string{"cluster": "prod"}}, expectedURL: "http://thanos"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expectedURL, backends.ClientFor(tt.fileName, tt.group, tt.rule).url)
+		})
+	}
+
+	var noBackends *Backends
+	assert.Nil(t, noBackends.ClientFor("rules/foo.yaml", unmarshaler.RuleGroup{}, nil))
+}
@@ -1,173 +1,559 @@
 package prometheus
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
 	"os"
+	"path/filepath"
+	"slices"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	log "github.com/sirupsen/logrus"
 )
 
-func newCache(file, prometheusURL string, maxAge time.Duration) *cache {
-	emptyCache := cache{
-		file:          file,
-		PrometheusURL: prometheusURL,
-		Created:       time.Now(),
-		SourceTenants: make(map[string]*cacheData),
-		mtx:           sync.RWMutex{},
-	}
-	previousCache := &emptyCache
-	f, err := os.Open(file)
-	if err != nil {
-		if os.IsNotExist(err) {
-			f, err = os.Create(file)
-			if err != nil {
-				log.WithError(err).WithField("file", file).Warn("error creating cache file")
-				return &emptyCache
-			}
-			emptyCacheJSON, err := json.Marshal(&emptyCache)
-			if err != nil {
-				log.WithError(err).WithField("file", file).Warn("error creating cache file")
-				return &emptyCache
-			}
-			_, err = f.Write(emptyCacheJSON)
-			if err != nil {
-				log.WithError(err).WithField("file", file).Warn("error writing empty cache file")
-				return &emptyCache
-			}
-			f.Close()
-		} else {
-			log.WithError(err).WithField("file", file).Warn("error opening cache file, skipping")
-			return &emptyCache
+// cacheFormatVersion is increased on incompatible changes of the cache format, cache files of other versions are ignored.
+const cacheFormatVersion = 3
+
+// cacheEntry is a cached value with the time it was obtained, so each entry expires on its own.
+type cacheEntry[T any] struct {
+	Value     T         `json:"value"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+func newCacheEntry[T any](value T) cacheEntry[T] {
+	return cacheEntry[T]{Value: value, Timestamp: time.Now()}
+}
+
+// expired returns true if the entry is older than the ttl, zero ttl means the entry never expires.
+func (e cacheEntry[T]) expired(ttl time.Duration) bool {
+	return ttl != 0 && time.Since(e.Timestamp) > ttl
+}
+
+// mergeEntries adds entries of the other map which are missing or newer.
+func mergeEntries[T any](entries, other map[string]cacheEntry[T]) {
+	for key, entry := range other {
+		if current, ok := entries[key]; !ok || entry.Timestamp.After(current.Timestamp) {
+			entries[key] = entry
 		}
 	}
-	if err := json.NewDecoder(f).Decode(&previousCache); err != nil {
-		log.WithError(err).WithField("file", file).Warn("invalid cache file format")
-		return &emptyCache
-	}
-	pruneCache := false
-	cacheAge := time.Since(previousCache.Created)
-	if maxAge != 0 && cacheAge > maxAge {
-		log.WithFields(log.Fields{
-			"cacheAge":    cacheAge,
-			"maxCacheAge": maxAge,
-			"file_name":   file,
-		}).Info("cache is outdated")
-		pruneCache = true
-	}
-	if previousCache.PrometheusURL != prometheusURL {
-		log.WithFields(log.Fields{
-			"previousPrometheusURL": previousCache.PrometheusURL,
-			"newPrometheusURL":      prometheusURL,
-			"file_name":             file,
-		}).Info("data in cache file are from different Prometheus, cannot be used")
-		pruneCache = true
-	}
-	if pruneCache {
-		log.WithField("file", file).Warn("Pruning cache file")
-		return &emptyCache
-	}
-	return previousCache
 }
 
+// pruneEntries deletes the expired entries and returns their number.
+func pruneEntries[T any](entries map[string]cacheEntry[T], ttl time.Duration) int {
+	before := len(entries)
+	maps.DeleteFunc(entries, func(_ string, entry cacheEntry[T]) bool { return entry.expired(ttl) })
+	return before - len(entries)
+}
+
+// cacheRunStats are the cache hits and misses of the last run which used the data.
+type cacheRunStats struct {
+	Time   time.Time `json:"time"`
+	Hits   int       `json:"hits"`
+	Misses int       `json:"misses"`
+}
+
+// queryStats are the cached QueryStats, including the error of the failed query.
 type queryStats struct {
-	Error    error         `json:"error,omitempty"`
-	Series   int           `json:"series"`
-	Duration time.Duration `json:"duration"`
+	QueryStats
+	Error string `json:"error,omitempty"`
 }
 
+// cacheData are cached responses of a single Prometheus for the given source tenants and client settings.
 type cacheData struct {
-	QueriesStats           map[string]queryStats `json:"queries_stats"`
-	KnownLabels            []string              `json:"known_labels"`
-	SelectorMatchingSeries map[string]int        `json:"selector_matching_series"`
-	mtx                    sync.RWMutex          `json:"-"`
+	// PrometheusURL and SourceTenants are informative only, the data are identified by the namespace.
+	PrometheusURL          string                            `json:"prometheus_url"`
+	SourceTenants          []string                          `json:"source_tenants,omitempty"`
+	TTL                    time.Duration                     `json:"ttl"`
+	QueriesStats           map[string]cacheEntry[queryStats] `json:"queries_stats"`
+	KnownLabels            *cacheEntry[[]string]             `json:"known_labels,omitempty"`
+	SelectorMatchingSeries map[string]cacheEntry[int]        `json:"selector_matching_series"`
+	// MetricsSeries is the number of series of the metrics with the most series from the TSDB status.
+	MetricsSeries         *cacheEntry[map[string]int] `json:"metrics_series,omitempty"`
+	ExpressionCardinality map[string]cacheEntry[int]  `json:"expression_cardinality,omitempty"`
+	LastRun               *cacheRunStats              `json:"last_run,omitempty"`
+	mtx                   sync.RWMutex
+	hits, misses          atomic.Int64
+}
+
+func newCacheData(prometheusURL string, sourceTenants []string, ttl time.Duration) *cacheData {
+	return &cacheData{
+		PrometheusURL:          prometheusURL,
+		SourceTenants:          sourceTenants,
+		TTL:                    ttl,
+		QueriesStats:           make(map[string]cacheEntry[queryStats]),
+		SelectorMatchingSeries: make(map[string]cacheEntry[int]),
+		ExpressionCardinality:  make(map[string]cacheEntry[int]),
+	}
+}
+
+// countLookup counts the cache hit or miss and returns whether it was a hit.
+func (c *cacheData) countLookup(hit bool) bool {
+	if hit {
+		c.hits.Add(1)
+	} else {
+		c.misses.Add(1)
+	}
+	return hit
+}
+
+// finishRun stores the hits and misses counted since the last call as the last run stats, if there were any lookups.
+func (c *cacheData) finishRun() {
+	hits, misses := c.hits.Swap(0), c.misses.Swap(0)
+	if hits+misses == 0 {
+		return
+	}
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	c.LastRun = &cacheRunStats{Time: time.Now(), Hits: int(hits), Misses: int(misses)}
 }
 
 func (c *cacheData) MatchingSeriesForSelector(selector string) (int, bool) {
 	c.mtx.RLock()
 	defer c.mtx.RUnlock()
-	count, found := c.SelectorMatchingSeries[selector]
-	return count, found
+	entry, found := c.SelectorMatchingSeries[selector]
+	if !c.countLookup(found && !entry.expired(c.TTL)) {
+		return 0, false
+	}
+	return entry.Value, true
 }
 
 func (c *cacheData) SetSelectorMatchingSeries(selector string, count int) {
 	c.mtx.Lock()
 	defer c.mtx.Unlock()
-	c.SelectorMatchingSeries[selector] = count
+	c.SelectorMatchingSeries[selector] = newCacheEntry(count)
 }
 
 func (c *cacheData) GetKnownLabels() []string {
 	c.mtx.RLock()
 	defer c.mtx.RUnlock()
-	return c.KnownLabels
+	if !c.countLookup(c.KnownLabels != nil && !c.KnownLabels.expired(c.TTL)) {
+		return nil
+	}
+	return c.KnownLabels.Value
 }
 
 func (c *cacheData) SetKnownLabels(labels []string) {
 	c.mtx.Lock()
 	defer c.mtx.Unlock()
-	c.KnownLabels = labels
+	entry := newCacheEntry(labels)
+	c.KnownLabels = &entry
 }
 
 func (c *cacheData) GetQueryStats(query string) (queryStats, bool) {
 	c.mtx.RLock()
 	defer c.mtx.RUnlock()
-	stats, found := c.QueriesStats[query]
-	return stats, found
+	entry, found := c.QueriesStats[query]
+	if !c.countLookup(found && !entry.expired(c.TTL)) {
+		return queryStats{}, false
+	}
+	return entry.Value, true
 }
 
 func (c *cacheData) SetQueryStats(query string, stats queryStats) {
 	c.mtx.Lock()
 	defer c.mtx.Unlock()
-	c.QueriesStats[query] = stats
+	c.QueriesStats[query] = newCacheEntry(stats)
 }
 
-type cache struct {
-	file          string
-	PrometheusURL string                `json:"prometheus_url"`
-	Created       time.Time             `json:"created"`
-	SourceTenants map[string]*cacheData `json:"source_tenants"`
-	mtx           sync.RWMutex          `json:"-"`
+func (c *cacheData) GetMetricsSeries() map[string]int {
+	c.mtx.RLock()
+	defer c.mtx.RUnlock()
+	if !c.countLookup(c.MetricsSeries != nil && !c.MetricsSeries.expired(c.TTL)) {
+		return nil
+	}
+	return c.MetricsSeries.Value
 }
 
-func (c *cache) SourceTenantsData(sourceTenants []string) *cacheData {
+func (c *cacheData) SetMetricsSeries(metricsSeries map[string]int) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	entry := newCacheEntry(metricsSeries)
+	c.MetricsSeries = &entry
+}
+
+func (c *cacheData) GetExpressionCardinality(expr string) (int, bool) {
 	c.mtx.RLock()
-	key := sourceTenantsToHeader(sourceTenants)
-	data, found := c.SourceTenants[key]
-	if found {
-		c.mtx.RUnlock()
-		return data
-	}
-	c.mtx.RUnlock()
-	data = &cacheData{
-		QueriesStats:           make(map[string]queryStats),
-		SelectorMatchingSeries: make(map[string]int),
-		KnownLabels:            []string{},
-		mtx:                    sync.RWMutex{},
+	defer c.mtx.RUnlock()
+	entry, found := c.ExpressionCardinality[expr]
+	if !c.countLookup(found && !entry.expired(c.TTL)) {
+		return 0, false
 	}
+	return entry.Value, true
+}
+
+func (c *cacheData) SetExpressionCardinality(expr string, cardinality int) {
 	c.mtx.Lock()
 	defer c.mtx.Unlock()
-	c.SourceTenants[key] = data
-	return data
+	c.ExpressionCardinality[expr] = newCacheEntry(cardinality)
 }
 
-func (c *cache) Dump() {
-	f, err := os.Create(c.file)
-	if err != nil {
-		log.WithError(err).WithField("file", c.file).Warn("failed to create cache file")
-		return
+// merge adds the missing or newer entries of the other data, its TTL takes precedence.
+func (c *cacheData) merge(other *cacheData) {
+	other.mtx.RLock()
+	defer other.mtx.RUnlock()
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	c.PrometheusURL = other.PrometheusURL
+	c.SourceTenants = other.SourceTenants
+	c.TTL = other.TTL
+	if other.KnownLabels != nil && (c.KnownLabels == nil || other.KnownLabels.Timestamp.After(c.KnownLabels.Timestamp)) {
+		c.KnownLabels = other.KnownLabels
+	}
+	if other.MetricsSeries != nil && (c.MetricsSeries == nil || other.MetricsSeries.Timestamp.After(c.MetricsSeries.Timestamp)) {
+		c.MetricsSeries = other.MetricsSeries
 	}
-	defer func(f *os.File) {
-		_ = f.Close()
-	}(f)
-	e := json.NewEncoder(f)
-	e.SetIndent("", "")
+	if other.LastRun != nil && (c.LastRun == nil || other.LastRun.Time.After(c.LastRun.Time)) {
+		c.LastRun = other.LastRun
+	}
+	mergeEntries(c.QueriesStats, other.QueriesStats)
+	mergeEntries(c.SelectorMatchingSeries, other.SelectorMatchingSeries)
+	mergeEntries(c.ExpressionCardinality, other.ExpressionCardinality)
+}
+
+// prune deletes the entries older than the ttl and returns their number.
+func (c *cacheData) prune(ttl time.Duration) int {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	pruned := 0
+	if c.KnownLabels != nil && c.KnownLabels.expired(ttl) {
+		c.KnownLabels = nil
+		pruned++
+	}
+	pruned += pruneEntries(c.QueriesStats, ttl)
+	if c.MetricsSeries != nil && c.MetricsSeries.expired(ttl) {
+		c.MetricsSeries = nil
+		pruned++
+	}
+	pruned += pruneEntries(c.SelectorMatchingSeries, ttl)
+	pruned += pruneEntries(c.ExpressionCardinality, ttl)
+	return pruned
+}
+
+// entries returns number of the cached entries.
+func (c *cacheData) entries() int {
 	c.mtx.RLock()
 	defer c.mtx.RUnlock()
-	err = e.Encode(c)
+	entries := len(c.QueriesStats) + len(c.SelectorMatchingSeries) + len(c.ExpressionCardinality)
+	if c.KnownLabels != nil {
+		entries++
+	}
+	if c.MetricsSeries != nil {
+		entries++
+	}
+	return entries
+}
+
+// cacheFileContent is the format of the persisted cache, the data are stored by their namespace.
+type cacheFileContent struct {
+	Version    int                   `json:"version"`
+	Namespaces map[string]*cacheData `json:"namespaces"`
+}
+
+// readCacheFile returns the namespaces stored in the file, missing file or file of an incompatible version is treated as empty.
+func readCacheFile(file string) (map[string]*cacheData, error) {
+	namespaces := map[string]*cacheData{}
+	f, err := os.Open(file)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return namespaces, nil
+		}
+		return namespaces, fmt.Errorf("error opening cache file: %w", err)
+	}
+	defer f.Close()
+	content, err := decodeCacheContent(f)
+	if err != nil {
+		return namespaces, err
+	}
+	if content.Version != cacheFormatVersion {
+		log.WithFields(log.Fields{"file": file, "version": content.Version, "expectedVersion": cacheFormatVersion}).Info("cache file has incompatible version, ignoring it")
+		return namespaces, nil
+	}
+	return content.Namespaces, nil
+}
+
+// decodeCacheContent decodes the cache in the file format, the namespaces are initialized so they can be used directly.
+func decodeCacheContent(r io.Reader) (cacheFileContent, error) {
+	var content cacheFileContent
+	if err := json.NewDecoder(r).Decode(&content); err != nil {
+		return content, fmt.Errorf("invalid cache file format: %w", err)
+	}
+	namespaces := make(map[string]*cacheData, len(content.Namespaces))
+	for namespace, data := range content.Namespaces {
+		if data == nil {
+			continue
+		}
+		if data.QueriesStats == nil {
+			data.QueriesStats = make(map[string]cacheEntry[queryStats])
+		}
+		if data.SelectorMatchingSeries == nil {
+			data.SelectorMatchingSeries = make(map[string]cacheEntry[int])
+		}
+		if data.ExpressionCardinality == nil {
+			data.ExpressionCardinality = make(map[string]cacheEntry[int])
+		}
+		namespaces[namespace] = data
+	}
+	content.Namespaces = namespaces
+	return content, nil
+}
+
+// writeFileAtomic writes the file using rename of a temporary file, so readers never see a partially written file.
+func writeFileAtomic(file string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(file), filepath.Base(file)+".tmp-*")
 	if err != nil {
-		log.WithError(err).Warn("failed to write cache data")
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), file)
+}
+
+// updateCacheFile applies the update to the namespaces currently stored in the file and atomically replaces it, namespaces without entries are dropped.
+// The file is locked for the whole update, so concurrent promruval processes sharing the cache do not lose each other's entries.
+func updateCacheFile(file string, update func(stored map[string]*cacheData)) error {
+	unlock, err := lockFile(file + ".lock")
+	if err != nil {
+		return fmt.Errorf("failed to lock cache file: %w", err)
+	}
+	defer unlock()
+	stored, err := readCacheFile(file)
+	if err != nil {
+		log.WithError(err).WithField("file", file).Warn("failed to read current cache file, overwriting it")
+	}
+	update(stored)
+	maps.DeleteFunc(stored, func(_ string, data *cacheData) bool { return data.entries() == 0 })
+	content, err := json.Marshal(cacheFileContent{Version: cacheFormatVersion, Namespaces: stored})
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(file, content)
+}
+
+// mergeNamespaces merges the namespaces to the stored ones and prunes all of them using their own TTL.
+func mergeNamespaces(stored, namespaces map[string]*cacheData) {
+	for namespace, data := range namespaces {
+		current, ok := stored[namespace]
+		if !ok {
+			current = newCacheData(data.PrometheusURL, data.SourceTenants, data.TTL)
+			stored[namespace] = current
+		}
+		current.merge(data)
+	}
+	for _, data := range stored {
+		data.prune(data.TTL)
+	}
+}
+
+// pruneNamespaces deletes entries older than the maxAge, or expired by their own TTL if the maxAge is zero, and returns their number.
+func pruneNamespaces(stored map[string]*cacheData, maxAge time.Duration) int {
+	pruned := 0
+	for _, data := range stored {
+		ttl := maxAge
+		if ttl == 0 {
+			ttl = data.TTL
+		}
+		pruned += data.prune(ttl)
+	}
+	return pruned
+}
+
+// cacheStore persists the cached data.
+type cacheStore interface {
+	// load returns the stored data of the namespace, nil if there are none.
+	load(namespace string) (*cacheData, error)
+	// loadAll returns all the stored namespaces.
+	loadAll() (map[string]*cacheData, error)
+	// merge merges the namespaces with the currently stored ones.
+	merge(namespaces map[string]*cacheData) error
+	// prune deletes entries older than the maxAge, or expired by their own TTL if the maxAge is zero, and returns their number.
+	prune(maxAge time.Duration) (int, error)
+	String() string
+}
+
+// fileCacheStore stores all the namespaces in a single file.
+type fileCacheStore struct {
+	file       string
+	namespaces map[string]*cacheData
+}
+
+func (s *fileCacheStore) load(namespace string) (*cacheData, error) {
+	if s.namespaces == nil {
+		namespaces, err := readCacheFile(s.file)
+		s.namespaces = namespaces
+		if err != nil {
+			return nil, err
+		}
+	}
+	return s.namespaces[namespace], nil
+}
+
+func (s *fileCacheStore) loadAll() (map[string]*cacheData, error) {
+	return readCacheFile(s.file)
+}
+
+func (s *fileCacheStore) merge(namespaces map[string]*cacheData) error {
+	return updateCacheFile(s.file, func(stored map[string]*cacheData) { mergeNamespaces(stored, namespaces) })
+}
+
+func (s *fileCacheStore) prune(maxAge time.Duration) (int, error) {
+	pruned := 0
+	err := updateCacheFile(s.file, func(stored map[string]*cacheData) { pruned = pruneNamespaces(stored, maxAge) })
+	return pruned, err
+}
+
+func (s *fileCacheStore) String() string {
+	return s.file
+}
+
+// dirCacheStore stores each namespace in a separate file of the directory, so only the used namespaces are loaded and locked.
+type dirCacheStore struct {
+	dir string
+}
+
+func (s *dirCacheStore) namespaceFile(namespace string) string {
+	return filepath.Join(s.dir, namespace+".json")
+}
+
+func (s *dirCacheStore) load(namespace string) (*cacheData, error) {
+	namespaces, err := readCacheFile(s.namespaceFile(namespace))
+	if err != nil {
+		return nil, err
+	}
+	return namespaces[namespace], nil
+}
+
+func (s *dirCacheStore) files() ([]string, error) {
+	return filepath.Glob(filepath.Join(s.dir, "*.json"))
+}
+
+func (s *dirCacheStore) loadAll() (map[string]*cacheData, error) {
+	files, err := s.files()
+	if err != nil {
+		return nil, err
+	}
+	namespaces := map[string]*cacheData{}
+	var errs []error
+	for _, file := range files {
+		stored, err := readCacheFile(file)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", file, err))
+		}
+		maps.Copy(namespaces, stored)
+	}
+	return namespaces, errors.Join(errs...)
+}
+
+func (s *dirCacheStore) merge(namespaces map[string]*cacheData) error {
+	if err := os.MkdirAll(s.dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create cache directory: %w", err)
+	}
+	var errs []error
+	for namespace, data := range namespaces {
+		update := func(stored map[string]*cacheData) { mergeNamespaces(stored, map[string]*cacheData{namespace: data}) }
+		if err := updateCacheFile(s.namespaceFile(namespace), update); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (s *dirCacheStore) prune(maxAge time.Duration) (int, error) {
+	files, err := s.files()
+	if err != nil {
+		return 0, err
+	}
+	pruned := 0
+	var errs []error
+	for _, file := range files {
+		if err := updateCacheFile(file, func(stored map[string]*cacheData) { pruned += pruneNamespaces(stored, maxAge) }); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return pruned, errors.Join(errs...)
+}
+
+func (s *dirCacheStore) String() string {
+	return s.dir
+}
+
+// cacheKey identifies the client settings affecting the responses, header values are part of the key only as a hash.
+func cacheKey(prometheusURL string, queryOffset, queryLookback time.Duration, headers map[string]string) string {
+	key := fmt.Sprintf("url=%s\noffset=%s\nlookback=%s\n", prometheusURL, queryOffset, queryLookback)
+	for _, name := range slices.Sorted(maps.Keys(headers)) {
+		key += fmt.Sprintf("header=%s:%s\n", name, headers[name])
+	}
+	return key
+}
+
+func newCache(store cacheStore, key, prometheusURL string, ttl time.Duration) *cache {
+	return &cache{
+		store:         store,
+		key:           key,
+		prometheusURL: prometheusURL,
+		ttl:           ttl,
+		namespaces:    make(map[string]*cacheData),
+	}
+}
+
+// cache of the Prometheus responses, loaded lazily from the store for each namespace.
+type cache struct {
+	store         cacheStore
+	key           string
+	prometheusURL string
+	ttl           time.Duration
+	namespaces    map[string]*cacheData
+	mtx           sync.Mutex
+}
+
+// namespace returns the identifier of the data for the source tenants with the cache key of the client.
+func (c *cache) namespace(sourceTenants []string) string {
+	hash := sha256.Sum256([]byte(c.key + "tenants=" + sourceTenantsToHeader(sourceTenants)))
+	return hex.EncodeToString(hash[:16])
+}
+
+func (c *cache) SourceTenantsData(sourceTenants []string) *cacheData {
+	namespace := c.namespace(sourceTenants)
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	if data, found := c.namespaces[namespace]; found {
+		return data
+	}
+	data := newCacheData(c.prometheusURL, slices.Sorted(slices.Values(sourceTenants)), c.ttl)
+	stored, err := c.store.load(namespace)
+	if err != nil {
+		log.WithError(err).WithField("cache", c.store).Warn("failed to load cache, skipping")
+	}
+	if stored != nil {
+		// The stored TTL might come from a different configuration.
+		stored.TTL = c.ttl
+		data.merge(stored)
+	}
+	c.namespaces[namespace] = data
+	return data
+}
+
+func (c *cache) Dump() {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	for _, data := range c.namespaces {
+		data.finishRun()
+	}
+	if err := c.store.merge(c.namespaces); err != nil {
+		log.WithError(err).WithField("cache", c.store).Warn("failed to write cache data")
 		return
 	}
-	log.WithField("file_name", c.file).Info("successfully dumped cache to file")
+	log.WithField("cache", c.store).Info("successfully dumped cache")
 }
@@ -0,0 +1,167 @@
+package prometheus
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/fusakla/promruval/v3/pkg/config"
+)
+
+// newCacheStore returns the store of the cache configured in the Prometheus config.
+func newCacheStore(promConfig config.PrometheusConfig) cacheStore {
+	if promConfig.CacheDir != "" {
+		return &dirCacheStore{dir: promConfig.CacheDir}
+	}
+	return &fileCacheStore{file: promConfig.CacheFile}
+}
+
+// CacheLocation returns the file or directory the cache of the Prometheus config is stored in.
+func CacheLocation(promConfig config.PrometheusConfig) string {
+	return newCacheStore(promConfig).String()
+}
+
+// CacheRunStats are the cache hits and misses of the last run which used the cached data.
+type CacheRunStats struct {
+	Time    time.Time `json:"time" yaml:"time"`
+	Hits    int       `json:"hits" yaml:"hits"`
+	Misses  int       `json:"misses" yaml:"misses"`
+	HitRate float64   `json:"hit_rate" yaml:"hit_rate"`
+}
+
+// CacheNamespaceStats describes the cached data of a single Prometheus with the given source tenants and client settings.
+type CacheNamespaceStats struct {
+	Namespace      string         `json:"namespace" yaml:"namespace"`
+	PrometheusURL  string         `json:"prometheus_url" yaml:"prometheus_url"`
+	SourceTenants  []string       `json:"source_tenants,omitempty" yaml:"source_tenants,omitempty"`
+	TTL            time.Duration  `json:"ttl" yaml:"ttl"`
+	Entries        int            `json:"entries" yaml:"entries"`
+	ExpiredEntries int            `json:"expired_entries" yaml:"expired_entries"`
+	OldestEntry    time.Time      `json:"oldest_entry" yaml:"oldest_entry"`
+	NewestEntry    time.Time      `json:"newest_entry" yaml:"newest_entry"`
+	LastRun        *CacheRunStats `json:"last_run,omitempty" yaml:"last_run,omitempty"`
+}
+
+// CacheStats describes the cache stored in the file or directory.
+type CacheStats struct {
+	Cache      string                `json:"cache" yaml:"cache"`
+	Namespaces []CacheNamespaceStats `json:"namespaces" yaml:"namespaces"`
+}
+
+func (s CacheStats) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Cache %s:\n", s.Cache)
+	if len(s.Namespaces) == 0 {
+		b.WriteString("  empty\n")
+	}
+	for _, n := range s.Namespaces {
+		tenants := "none"
+		if len(n.SourceTenants) > 0 {
+			tenants = strings.Join(n.SourceTenants, ", ")
+		}
+		fmt.Fprintf(&b, "  %s (namespace %s, source tenants: %s):\n", n.PrometheusURL, n.Namespace, tenants)
+		fmt.Fprintf(&b, "    Entries: %d and %d of them expired (TTL %s)\n", n.Entries, n.ExpiredEntries, n.TTL)
+		if n.Entries > 0 {
+			fmt.Fprintf(&b, "    Age: oldest %s, newest %s\n", time.Since(n.OldestEntry).Round(time.Second), time.Since(n.NewestEntry).Round(time.Second))
+		}
+		if n.LastRun != nil {
+			fmt.Fprintf(&b, "    Last run: %s ago with %d hits and %d misses (hit rate %.1f%%)\n", time.Since(n.LastRun.Time).Round(time.Second), n.LastRun.Hits, n.LastRun.Misses, n.LastRun.HitRate*100)
+		}
+	}
+	return b.String()
+}
+
+func (c *cacheData) stats(namespace string) CacheNamespaceStats {
+	c.mtx.RLock()
+	defer c.mtx.RUnlock()
+	stats := CacheNamespaceStats{
+		Namespace:     namespace,
+		PrometheusURL: c.PrometheusURL,
+		SourceTenants: c.SourceTenants,
+		TTL:           c.TTL,
+	}
+	addEntry := func(timestamp time.Time, expired bool) {
+		stats.Entries++
+		if expired {
+			stats.ExpiredEntries++
+		}
+		if stats.OldestEntry.IsZero() || timestamp.Before(stats.OldestEntry) {
+			stats.OldestEntry = timestamp
+		}
+		if timestamp.After(stats.NewestEntry) {
+			stats.NewestEntry = timestamp
+		}
+	}
+	if c.KnownLabels != nil {
+		addEntry(c.KnownLabels.Timestamp, c.KnownLabels.expired(c.TTL))
+	}
+	for _, entry := range c.QueriesStats {
+		addEntry(entry.Timestamp, entry.expired(c.TTL))
+	}
+	if c.MetricsSeries != nil {
+		addEntry(c.MetricsSeries.Timestamp, c.MetricsSeries.expired(c.TTL))
+	}
+	for _, entry := range c.SelectorMatchingSeries {
+		addEntry(entry.Timestamp, entry.expired(c.TTL))
+	}
+	for _, entry := range c.ExpressionCardinality {
+		addEntry(entry.Timestamp, entry.expired(c.TTL))
+	}
+	if c.LastRun != nil {
+		stats.LastRun = &CacheRunStats{Time: c.LastRun.Time, Hits: c.LastRun.Hits, Misses: c.LastRun.Misses}
+		if lookups := c.LastRun.Hits + c.LastRun.Misses; lookups > 0 {
+			stats.LastRun.HitRate = float64(c.LastRun.Hits) / float64(lookups)
+		}
+	}
+	return stats
+}
+
+// ReadCacheStats returns stats of the cache configured in the Prometheus config.
+func ReadCacheStats(promConfig config.PrometheusConfig) (CacheStats, error) {
+	store := newCacheStore(promConfig)
+	stats := CacheStats{Cache: store.String(), Namespaces: []CacheNamespaceStats{}}
+	namespaces, err := store.loadAll()
+	if err != nil {
+		return stats, err
+	}
+	for _, namespace := range slices.Sorted(maps.Keys(namespaces)) {
+		stats.Namespaces = append(stats.Namespaces, namespaces[namespace].stats(namespace))
+	}
+	return stats, nil
+}
+
+// PruneCache deletes entries older than the maxAge, or expired by their own TTL if the maxAge is zero, and returns their number.
+func PruneCache(promConfig config.PrometheusConfig, maxAge time.Duration) (int, error) {
+	return newCacheStore(promConfig).prune(maxAge)
+}
+
+// ExportCache writes all the cached data in the format of the cache file.
+func ExportCache(promConfig config.PrometheusConfig, w io.Writer) error {
+	namespaces, err := newCacheStore(promConfig).loadAll()
+	if err != nil {
+		return err
+	}
+	e := json.NewEncoder(w)
+	e.SetIndent("", "  ")
+	return e.Encode(cacheFileContent{Version: cacheFormatVersion, Namespaces: namespaces})
+}
+
+// ImportCache merges the cache exported by the ExportCache to the cache, newer entries take precedence. Returns the number of imported entries.
+func ImportCache(promConfig config.PrometheusConfig, r io.Reader) (int, error) {
+	content, err := decodeCacheContent(r)
+	if err != nil {
+		return 0, err
+	}
+	if content.Version != cacheFormatVersion {
+		return 0, fmt.Errorf("unsupported cache format version %d, expected %d", content.Version, cacheFormatVersion)
+	}
+	imported := 0
+	for _, data := range content.Namespaces {
+		imported += data.entries()
+	}
+	return imported, newCacheStore(promConfig).merge(content.Namespaces)
+}
@@ -0,0 +1,78 @@
+package prometheus
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fusakla/promruval/v3/pkg/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCacheAdmin(t *testing.T) {
+	tests := []struct {
+		name      string
+		newConfig func(dir string) config.PrometheusConfig
+	}{
+		{name: "file", newConfig: func(dir string) config.PrometheusConfig {
+			return config.PrometheusConfig{CacheFile: filepath.Join(dir, "cache.json")}
+		}},
+		{name: "directory", newConfig: func(dir string) config.PrometheusConfig {
+			return config.PrometheusConfig{CacheDir: filepath.Join(dir, "cache")}
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			promConfig := tt.newConfig(t.TempDir())
+			c := newCache(newCacheStore(promConfig), cacheKey("http://prometheus", 0, 0, nil), "http://prometheus", time.Hour)
+			data := c.SourceTenantsData([]string{"team-a"})
+			data.SetSelectorMatchingSeries("up", 1)
+			data.SelectorMatchingSeries["old"] = cacheEntry[int]{Value: 2, Timestamp: time.Now().Add(-2 * time.Hour)}
+			data.SetKnownLabels([]string{"job"})
+			data.MatchingSeriesForSelector("up")
+			data.MatchingSeriesForSelector("missing")
+			data.MatchingSeriesForSelector("old")
+			data.GetKnownLabels()
+			c.Dump()
+
+			stats, err := ReadCacheStats(promConfig)
+			require.NoError(t, err)
+			assert.Equal(t, CacheLocation(promConfig), stats.Cache)
+			require.Len(t, stats.Namespaces, 1)
+			namespace := stats.Namespaces[0]
+			assert.Equal(t, "http://prometheus", namespace.PrometheusURL)
+			assert.Equal(t, []string{"team-a"}, namespace.SourceTenants)
+			assert.Equal(t, 2, namespace.Entries)
+			assert.Equal(t, 0, namespace.ExpiredEntries)
+			require.NotNil(t, namespace.LastRun)
+			assert.Equal(t, 2, namespace.LastRun.Hits)
+			assert.Equal(t, 2, namespace.LastRun.Misses)
+			assert.InDelta(t, 0.5, namespace.LastRun.HitRate, 0.001)
+			assert.Contains(t, stats.String(), "hit rate 50.0%")
+
+			var exported bytes.Buffer
+			require.NoError(t, ExportCache(promConfig, &exported))
+
+			pruned, err := PruneCache(promConfig, time.Nanosecond)
+			require.NoError(t, err)
+			assert.Equal(t, 2, pruned)
+			stats, err = ReadCacheStats(promConfig)
+			require.NoError(t, err)
+			assert.Empty(t, stats.Namespaces)
+
+			imported, err := ImportCache(promConfig, &exported)
+			require.NoError(t, err)
+			assert.Equal(t, 2, imported)
+			c = newCache(newCacheStore(promConfig), cacheKey("http://prometheus", 0, 0, nil), "http://prometheus", time.Hour)
+			count, found := c.SourceTenantsData([]string{"team-a"}).MatchingSeriesForSelector("up")
+			assert.True(t, found)
+			assert.Equal(t, 1, count)
+
+			_, err = ImportCache(promConfig, strings.NewReader(`{"version": 1, "namespaces": {}}`))
+			assert.ErrorContains(t, err, "unsupported cache format version 1")
+		})
+	}
+}
@@ -0,0 +1,8 @@
+//go:build !unix && !windows
+
+package prometheus
+
+// lockFile is a no-op on platforms without file locking, the atomic rename still prevents corrupted cache files.
+func lockFile(_ string) (func(), error) {
+	return func() {}, nil
+}
@@ -0,0 +1,120 @@
+package prometheus
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCacheEntryExpiration(t *testing.T) {
+	data := newCacheData("http://prometheus", nil, time.Minute)
+	data.SetSelectorMatchingSeries("up", 1)
+	data.SetKnownLabels([]string{"job"})
+	data.SetQueryStats("up", queryStats{QueryStats: QueryStats{Series: 1}})
+	data.SelectorMatchingSeries["old"] = cacheEntry[int]{Value: 2, Timestamp: time.Now().Add(-2 * time.Minute)}
+
+	count, found := data.MatchingSeriesForSelector("up")
+	assert.True(t, found)
+	assert.Equal(t, 1, count)
+	_, found = data.MatchingSeriesForSelector("old")
+	assert.False(t, found)
+	assert.Equal(t, []string{"job"}, data.GetKnownLabels())
+
+	assert.Equal(t, 1, data.prune(data.TTL))
+	assert.NotContains(t, data.SelectorMatchingSeries, "old")
+	assert.Equal(t, 3, data.entries())
+
+	data.TTL = time.Nanosecond
+	time.Sleep(time.Millisecond)
+	assert.Nil(t, data.GetKnownLabels())
+	assert.Equal(t, 3, data.prune(data.TTL))
+	assert.Equal(t, 0, data.entries())
+}
+
+func TestCacheKey(t *testing.T) {
+	base := newCache(nil, cacheKey("http://prometheus", time.Minute, 20*time.Minute, map[string]string{"X-Foo": "foo"}), "http://prometheus", time.Hour)
+	tests := []struct {
+		name          string
+		key           string
+		sourceTenants []string
+		same          bool
+	}{
+		{name: "same", key: cacheKey("http://prometheus", time.Minute, 20*time.Minute, map[string]string{"X-Foo": "foo"}), same: true},
+		{name: "url", key: cacheKey("http://thanos", time.Minute, 20*time.Minute, map[string]string{"X-Foo": "foo"})},
+		{name: "offset", key: cacheKey("http://prometheus", 0, 20*time.Minute, map[string]string{"X-Foo": "foo"})},
+		{name: "lookback", key: cacheKey("http://prometheus", time.Minute, time.Hour, map[string]string{"X-Foo": "foo"})},
+		{name: "header", key: cacheKey("http://prometheus", time.Minute, 20*time.Minute, map[string]string{"X-Foo": "bar"})},
+		{name: "sourceTenants", key: cacheKey("http://prometheus", time.Minute, 20*time.Minute, map[string]string{"X-Foo": "foo"}), sourceTenants: []string{"team-a"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newCache(nil, tt.key, "http://prometheus", time.Hour)
+			assert.Equal(t, tt.same, base.namespace(nil) == c.namespace(tt.sourceTenants))
+		})
+	}
+}
+
+func TestCacheConcurrentDumps(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name     string
+		newStore func() cacheStore
+	}{
+		{name: "file", newStore: func() cacheStore { return &fileCacheStore{file: filepath.Join(dir, "cache.json")} }},
+		{name: "directory", newStore: func() cacheStore { return &dirCacheStore{dir: filepath.Join(dir, "cache")} }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key := cacheKey("http://prometheus", 0, 0, nil)
+			// Caches of concurrent jobs sharing the workspace, each of them knows different selectors.
+			var wg sync.WaitGroup
+			for i := range 10 {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					c := newCache(tt.newStore(), key, "http://prometheus", time.Hour)
+					c.SourceTenantsData([]string{"team-a"}).SetSelectorMatchingSeries(string(rune('a'+i)), i)
+					c.SourceTenantsData(nil).SetKnownLabels([]string{"job"})
+					c.Dump()
+				}()
+			}
+			wg.Wait()
+
+			c := newCache(tt.newStore(), key, "http://prometheus", time.Hour)
+			for i := range 10 {
+				count, found := c.SourceTenantsData([]string{"team-a"}).MatchingSeriesForSelector(string(rune('a' + i)))
+				assert.True(t, found)
+				assert.Equal(t, i, count)
+			}
+			assert.Equal(t, []string{"job"}, c.SourceTenantsData(nil).GetKnownLabels())
+		})
+	}
+}
+
+func TestCacheInvalidFile(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{name: "corrupted", content: `{"version": 3, "namespaces": {`},
+		{name: "oldFormat", content: `{"prometheus_url": "http://prometheus", "created": "2024-01-01T00:00:00Z", "source_tenants": {}}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := filepath.Join(t.TempDir(), "cache.json")
+			require.NoError(t, os.WriteFile(file, []byte(tt.content), 0o600))
+			c := newCache(&fileCacheStore{file: file}, cacheKey("http://prometheus", 0, 0, nil), "http://prometheus", time.Hour)
+			assert.Nil(t, c.SourceTenantsData(nil).GetKnownLabels())
+			c.SourceTenantsData(nil).SetKnownLabels([]string{"job"})
+			c.Dump()
+
+			c = newCache(&fileCacheStore{file: file}, cacheKey("http://prometheus", 0, 0, nil), "http://prometheus", time.Hour)
+			assert.Equal(t, []string{"job"}, c.SourceTenantsData(nil).GetKnownLabels())
+		})
+	}
+}
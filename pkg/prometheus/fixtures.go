@@ -0,0 +1,221 @@
+package prometheus
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/fusakla/promruval/v3/pkg/config"
+	"github.com/grafana/dskit/user"
+	log "github.com/sirupsen/logrus"
+)
+
+// ignoredFixtureParams are not part of the fixture key, the time params change with every run so recorded responses are replayed for any time
+// and the stats param only adds statistics to the response.
+var ignoredFixtureParams = []string{"time", "start", "end", "stats"}
+
+// fixtureRequest identifies the recorded request.
+type fixtureRequest struct {
+	// Path is the API path relative to the Prometheus URL, for example /api/v1/query.
+	Path   string     `json:"path"`
+	Params url.Values `json:"params,omitempty"`
+	// Tenant is value of the X-Scope-OrgID header.
+	Tenant string `json:"tenant,omitempty"`
+}
+
+func (r fixtureRequest) key() string {
+	params := url.Values{}
+	for name, values := range r.Params {
+		params[name] = slices.Sorted(slices.Values(values))
+	}
+	return r.Path + "?" + params.Encode() + "#" + r.Tenant
+}
+
+type fixtureResponse struct {
+	// StatusCode defaults to 200 if not set.
+	StatusCode int `json:"status_code,omitempty"`
+	// Body is the JSON response of the Prometheus API, non JSON bodies are stored as a JSON string.
+	Body json.RawMessage `json:"body"`
+}
+
+// fixture is a recorded request and response, stored as a single JSON file in the fixtures directory.
+type fixture struct {
+	Request  fixtureRequest  `json:"request"`
+	Response fixtureResponse `json:"response"`
+}
+
+// newFixtureRequest returns the request identification with the params from both the URL and the form body.
+func newFixtureRequest(req *http.Request, basePath string) (fixtureRequest, error) {
+	params := req.URL.Query()
+	if req.Body != nil && req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return fixtureRequest{}, err
+		}
+		defer body.Close()
+		content, err := io.ReadAll(body)
+		if err != nil {
+			return fixtureRequest{}, err
+		}
+		formParams, err := url.ParseQuery(string(content))
+		if err != nil {
+			return fixtureRequest{}, err
+		}
+		for name, values := range formParams {
+			params[name] = append(params[name], values...)
+		}
+	}
+	for _, name := range ignoredFixtureParams {
+		params.Del(name)
+	}
+	if len(params) == 0 {
+		params = nil
+	}
+	return fixtureRequest{
+		Path:   "/" + strings.TrimPrefix(strings.TrimPrefix(req.URL.Path, basePath), "/"),
+		Params: params,
+		Tenant: req.Header.Get(user.OrgIDHeaderName),
+	}, nil
+}
+
+func fixtureFileName(request fixtureRequest) string {
+	hash := sha256.Sum256([]byte(request.key()))
+	return hex.EncodeToString(hash[:16]) + ".json"
+}
+
+// recordRoundTripper stores every response to the fixtures directory.
+type recordRoundTripper struct {
+	next     http.RoundTripper
+	dir      string
+	basePath string
+}
+
+func (rt *recordRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	request, err := newFixtureRequest(req, rt.basePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read request to record: %w", err)
+	}
+	resp, err := rt.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	recordedBody := json.RawMessage(body)
+	if !json.Valid(body) {
+		if recordedBody, err = json.Marshal(string(body)); err != nil {
+			return nil, err
+		}
+	}
+	content, err := json.MarshalIndent(fixture{Request: request, Response: fixtureResponse{StatusCode: resp.StatusCode, Body: recordedBody}}, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	file := filepath.Join(rt.dir, fixtureFileName(request))
+	if err := writeFileAtomic(file, content); err != nil {
+		return nil, fmt.Errorf("failed to record response: %w", err)
+	}
+	log.WithFields(log.Fields{"path": request.Path, "file": file}).Debug("recorded prometheus response")
+	return resp, nil
+}
+
+// replayRoundTripper serves the recorded responses without sending any requests, unknown requests fail.
+type replayRoundTripper struct {
+	dir      string
+	basePath string
+	fixtures map[string]fixture
+}
+
+// loadFixtures loads all the JSON files in the directory, so the fixtures can have any file name.
+func loadFixtures(dir string) (map[string]fixture, error) {
+	if _, err := os.Stat(dir); err != nil {
+		return nil, fmt.Errorf("invalid fixtures directory: %w", err)
+	}
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	fixtures := make(map[string]fixture, len(files))
+	for _, file := range files {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		var f fixture
+		if err := json.Unmarshal(content, &f); err != nil {
+			return nil, fmt.Errorf("invalid fixture %s: %w", file, err)
+		}
+		for _, name := range ignoredFixtureParams {
+			f.Request.Params.Del(name)
+		}
+		fixtures[f.Request.key()] = f
+	}
+	return fixtures, nil
+}
+
+func (rt *replayRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	request, err := newFixtureRequest(req, rt.basePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read request to replay: %w", err)
+	}
+	f, ok := rt.fixtures[request.key()]
+	if !ok {
+		return nil, fmt.Errorf("no recorded response in %s for request to %s with params %q and tenant %q", rt.dir, request.Path, request.Params.Encode(), request.Tenant)
+	}
+	body := []byte(f.Response.Body)
+	var text string
+	if err := json.Unmarshal(body, &text); err == nil {
+		body = []byte(text)
+	}
+	statusCode := f.Response.StatusCode
+	if statusCode == 0 {
+		statusCode = http.StatusOK
+	}
+	return &http.Response{
+		StatusCode: statusCode,
+		Status:     fmt.Sprintf("%d %s", statusCode, http.StatusText(statusCode)),
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body:       io.NopCloser(bytes.NewReader(body)),
+		Request:    req,
+	}, nil
+}
+
+// newModeRoundTripper wraps the round tripper according to the mode of the Prometheus config.
+func newModeRoundTripper(promConfig config.PrometheusConfig, tripper http.RoundTripper) (http.RoundTripper, error) {
+	if promConfig.Mode == config.PrometheusModeLive {
+		return tripper, nil
+	}
+	baseURL, err := url.Parse(promConfig.URL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid prometheus URL: %w", err)
+	}
+	basePath := strings.TrimSuffix(baseURL.Path, "/")
+	switch promConfig.Mode {
+	case config.PrometheusModeRecord:
+		if err := os.MkdirAll(promConfig.FixturesDir, 0o755); err != nil {
+			return nil, fmt.Errorf("failed to create fixtures directory: %w", err)
+		}
+		return &recordRoundTripper{next: tripper, dir: promConfig.FixturesDir, basePath: basePath}, nil
+	case config.PrometheusModeReplay:
+		fixtures, err := loadFixtures(promConfig.FixturesDir)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load fixtures: %w", err)
+		}
+		log.WithFields(log.Fields{"dir": promConfig.FixturesDir, "fixtures": len(fixtures)}).Info("replaying recorded prometheus responses")
+		return &replayRoundTripper{dir: promConfig.FixturesDir, basePath: basePath, fixtures: fixtures}, nil
+	}
+	return nil, fmt.Errorf("unknown prometheus mode %s", promConfig.Mode)
+}
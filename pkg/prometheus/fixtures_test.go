@@ -0,0 +1,88 @@
+package prometheus
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/fusakla/promruval/v3/pkg/config"
+	"github.com/grafana/dskit/user"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClientRecordReplay(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/prometheus/api/v1/query":
+			// Number of returned series differs by the tenant, so the replayed responses show the tenant was part of the key.
+			_, _ = fmt.Fprintf(w, `{"status":"success","data":{"resultType":"vector","result":[%s]}}`, map[string]string{"": `{"metric":{},"value":[0,"1"]}`, "team-a": `{"metric":{},"value":[0,"1"]},{"metric":{},"value":[0,"1"]}`}[r.Header.Get(user.OrgIDHeaderName)])
+		case "/prometheus/api/v1/labels":
+			_, _ = w.Write([]byte(`{"status":"success","data":["__name__","job"]}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	dir := filepath.Join(t.TempDir(), "fixtures")
+	promConfig := config.PrometheusConfig{URL: server.URL + "/prometheus", Mode: config.PrometheusModeRecord, FixturesDir: dir}
+
+	recordClient, err := NewClient(promConfig)
+	require.NoError(t, err)
+	_, series, _, err := recordClient.Query("up", nil)
+	require.NoError(t, err)
+	assert.Equal(t, 1, series)
+	_, series, _, err = recordClient.Query("up", []string{"team-a"})
+	require.NoError(t, err)
+	assert.Equal(t, 2, series)
+	labels, err := recordClient.Labels(nil)
+	require.NoError(t, err)
+	server.Close()
+
+	fixtures, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	require.NoError(t, err)
+	assert.Len(t, fixtures, 3)
+
+	promConfig.Mode = config.PrometheusModeReplay
+	// Header files with credentials are not needed and might not be available offline.
+	promConfig.HTTPHeadersFile = map[string]string{"Authorization": filepath.Join(t.TempDir(), "missing")}
+	replayClient, err := NewClient(promConfig)
+	require.NoError(t, err)
+	_, series, _, err = replayClient.Query("up", nil)
+	require.NoError(t, err)
+	assert.Equal(t, 1, series)
+	_, series, _, err = replayClient.Query("up", []string{"team-a"})
+	require.NoError(t, err)
+	assert.Equal(t, 2, series)
+	replayedLabels, err := replayClient.Labels(nil)
+	require.NoError(t, err)
+	assert.Equal(t, labels, replayedLabels)
+	_, _, _, err = replayClient.Query("down", nil)
+	assert.ErrorContains(t, err, "no recorded response")
+}
+
+func TestClientReplayHandWrittenFixture(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "up.json"), []byte(`{
+  "request": {"path": "/api/v1/query", "params": {"query": ["up"], "time": ["1700000000"]}},
+  "response": {"body": {"status": "success", "data": {"resultType": "vector", "result": [{"metric": {}, "value": [0, "1"]}]}}}
+}`), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "error.json"), []byte(`{
+  "request": {"path": "/api/v1/query", "params": {"query": ["error"]}},
+  "response": {"status_code": 502, "body": "bad gateway"}
+}`), 0o600))
+	client, err := NewClient(config.PrometheusConfig{URL: "http://prometheus", Mode: config.PrometheusModeReplay, FixturesDir: dir})
+	require.NoError(t, err)
+
+	_, series, _, err := client.Query("up", nil)
+	require.NoError(t, err)
+	assert.Equal(t, 1, series)
+	_, _, _, err = client.Query("error", nil)
+	assert.ErrorContains(t, err, "502")
+
+	_, err = NewClient(config.PrometheusConfig{URL: "http://prometheus", Mode: config.PrometheusModeReplay, FixturesDir: filepath.Join(dir, "missing")})
+	assert.ErrorContains(t, err, "invalid fixtures directory")
+}
@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/fusakla/promruval/v3/pkg/config"
+	v1 "github.com/prometheus/client_golang/api/prometheus/v1"
 	"github.com/prometheus/common/model"
 )
 
@@ -30,6 +31,61 @@ func NewQueryVectorResponseMock(seriesCount int) interface{} {
 	return s
 }
 
+// NewQueryValueResponseMock returns a vector with a single sample of the value, like the result of an aggregation.
+func NewQueryValueResponseMock(value float64) interface{} {
+	return struct {
+		ResultType string         `json:"resultType"`
+		Value      []model.Sample `json:"result"`
+	}{
+		ResultType: "vector",
+		Value:      []model.Sample{{Metric: model.Metric{}, Value: model.SampleValue(value)}},
+	}
+}
+
+// NewTSDBStatusResponseMock returns the TSDB status with the series count of the metrics.
+func NewTSDBStatusResponseMock(metricsSeries map[string]int) interface{} {
+	result := v1.TSDBResult{SeriesCountByMetricName: []v1.Stat{}}
+	for name, count := range metricsSeries {
+		result.SeriesCountByMetricName = append(result.SeriesCountByMetricName, v1.Stat{Name: name, Value: uint64(count)})
+	}
+	return result
+}
+
+// NewQueryStatsResponseMock returns a vector with the number of series and the query statistics returned with the `stats=all` param.
+func NewQueryStatsResponseMock(seriesCount int, evalTime time.Duration, samples int64) interface{} {
+	type timings struct {
+		EvalTotalTime float64 `json:"evalTotalTime"`
+	}
+	type samplesStats struct {
+		TotalQueryableSamples int64 `json:"totalQueryableSamples"`
+		PeakSamples           int64 `json:"peakSamples"`
+	}
+	type stats struct {
+		Timings timings      `json:"timings"`
+		Samples samplesStats `json:"samples"`
+	}
+	return struct {
+		ResultType string         `json:"resultType"`
+		Value      []model.Sample `json:"result"`
+		Stats      stats          `json:"stats"`
+	}{
+		ResultType: "vector",
+		Value:      make([]model.Sample, seriesCount),
+		Stats:      stats{Timings: timings{EvalTotalTime: evalTime.Seconds()}, Samples: samplesStats{TotalQueryableSamples: samples, PeakSamples: samples}},
+	}
+}
+
+// NewQueryMatrixResponseMock returns the matrix as a range query result.
+func NewQueryMatrixResponseMock(matrix model.Matrix) interface{} {
+	return struct {
+		ResultType string       `json:"resultType"`
+		Value      model.Matrix `json:"result"`
+	}{
+		ResultType: "matrix",
+		Value:      matrix,
+	}
+}
+
 func NewSeriesResponseMock(seriesCount int) interface{} {
 	d := make([]map[string]string, seriesCount)
 	for i := range d {
@@ -2,13 +2,16 @@ package prometheus
 
 import (
 	"context"
-	"crypto/tls"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"maps"
 	"net/http"
+	"net/url"
 	"os"
-	"sort"
+	"slices"
+	"strconv"
 	"strings"
-	"sync"
 	"time"
 
 	"github.com/fusakla/promruval/v3/pkg/config"
@@ -17,16 +20,47 @@ import (
 	v1 "github.com/prometheus/client_golang/api/prometheus/v1"
 	prom_config "github.com/prometheus/common/config"
 	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/promql/parser"
 	log "github.com/sirupsen/logrus"
+	"golang.org/x/time/rate"
 )
 
 const (
 	bearerTokenEnvVar = "PROMETHEUS_BEARER_TOKEN"
 	userAgent         = "promruval"
+	// tsdbStatusLimit is the number of metrics with the most series requested from the TSDB status.
+	tsdbStatusLimit = 1000
+	// maxRangeQueryPoints is the maximum number of points per series of a single range query allowed by Prometheus.
+	maxRangeQueryPoints = 11000
 )
 
 func sourceTenantsToHeader(sourceTenants []string) string {
-	return strings.Join(sort.StringSlice(sourceTenants), "|")
+	return strings.Join(slices.Sorted(slices.Values(sourceTenants)), "|")
+}
+
+type sourceTenantsContextKey struct{}
+
+// withSourceTenants returns context with the source tenants to be sent in the tenant header of requests using the context.
+func withSourceTenants(ctx context.Context, sourceTenants []string) context.Context {
+	if len(sourceTenants) == 0 {
+		return ctx
+	}
+	return context.WithValue(ctx, sourceTenantsContextKey{}, sourceTenants)
+}
+
+// sourceTenantsRoundTripper overrides the tenant header with the source tenants from the request context, so each request can use different tenants.
+type sourceTenantsRoundTripper struct {
+	next http.RoundTripper
+}
+
+func (rt *sourceTenantsRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	sourceTenants, ok := req.Context().Value(sourceTenantsContextKey{}).([]string)
+	if !ok {
+		return rt.next.RoundTrip(req)
+	}
+	req = req.Clone(req.Context())
+	req.Header.Set(user.OrgIDHeaderName, sourceTenantsToHeader(sourceTenants))
+	return rt.next.RoundTrip(req)
 }
 
 func loadBearerToken(promConfig config.PrometheusConfig) (string, error) {
@@ -45,82 +79,139 @@ func loadBearerToken(promConfig config.PrometheusConfig) (string, error) {
 	return strings.TrimSpace(bearerToken), nil
 }
 
-func NewClient(promConfig config.PrometheusConfig) (*Client, error) {
-	var tripper http.RoundTripper = &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: promConfig.InsecureSkipTLSVerify}}
+// loadHTTPHeaders returns the configured HTTP headers including the ones with values read from files.
+// The files are not read in the replay mode, since they usually contain credentials which might not be available offline.
+func loadHTTPHeaders(promConfig config.PrometheusConfig) (map[string]string, error) {
+	headers := maps.Clone(promConfig.HTTPHeaders)
+	if headers == nil {
+		headers = map[string]string{}
+	}
+	if promConfig.Mode == config.PrometheusModeReplay {
+		return headers, nil
+	}
+	for header, file := range promConfig.HTTPHeadersFile {
+		value, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read file %s of the header %s: %w", file, header, err)
+		}
+		headers[header] = strings.TrimSpace(string(value))
+	}
+	return headers, nil
+}
+
+// httpClientConfig returns the HTTP client config with the insecureSkipTlsVerify and bearer token shorthands applied.
+func httpClientConfig(promConfig config.PrometheusConfig) (prom_config.HTTPClientConfig, error) {
+	httpConfig := promConfig.HTTPClientConfig
+	if promConfig.InsecureSkipTLSVerify {
+		httpConfig.TLSConfig.InsecureSkipVerify = true
+	}
 	bearerToken, err := loadBearerToken(promConfig)
 	if err != nil {
-		return nil, fmt.Errorf("failed to load bearer token: %w", err)
+		return httpConfig, fmt.Errorf("failed to load bearer token: %w", err)
 	}
 	if bearerToken != "" {
-		tripper = prom_config.NewAuthorizationCredentialsRoundTripper("Bearer", prom_config.NewInlineSecret(bearerToken), tripper)
+		httpConfig.Authorization = &prom_config.Authorization{Type: "Bearer", Credentials: prom_config.Secret(bearerToken)}
+	}
+	if err := httpConfig.Validate(); err != nil {
+		return httpConfig, fmt.Errorf("invalid HTTP client config: %w", err)
+	}
+	return httpConfig, nil
+}
+
+func NewClient(promConfig config.PrometheusConfig) (*Client, error) {
+	// Replay mode does not send any requests, so it does not need the credentials which might not be available offline.
+	if promConfig.Mode == config.PrometheusModeReplay {
+		return NewClientWithRoundTripper(promConfig, http.DefaultTransport)
+	}
+	httpConfig, err := httpClientConfig(promConfig)
+	if err != nil {
+		return nil, err
+	}
+	tripper, err := prom_config.NewRoundTripperFromConfig(httpConfig, userAgent)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create HTTP client: %w", err)
 	}
 	return NewClientWithRoundTripper(promConfig, tripper)
 }
 
 func NewClientWithRoundTripper(promConfig config.PrometheusConfig, tripper http.RoundTripper) (*Client, error) {
+	tripper, err := newModeRoundTripper(promConfig, tripper)
+	if err != nil {
+		return nil, err
+	}
 	headers := prom_config.Headers{
 		Headers: map[string]prom_config.Header{
 			"User-Agent": {Values: []string{userAgent}},
 		},
 	}
-	originalSourceTenants := []string{}
-	for k, v := range promConfig.HTTPHeaders {
-		if k == user.OrgIDHeaderName {
-			originalSourceTenants = []string{v}
-		}
+	httpHeaders, err := loadHTTPHeaders(promConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load HTTP headers: %w", err)
+	}
+	for k, v := range httpHeaders {
 		headers.Headers[k] = prom_config.Header{Values: []string{v}}
 	}
+	stats := &requestStats{}
+	retryTripper := &retryRoundTripper{
+		next:       tripper,
+		maxRetries: promConfig.MaxRetries,
+		minBackoff: promConfig.RetryMinBackoff,
+		maxBackoff: promConfig.RetryMaxBackoff,
+		stats:      stats,
+	}
+	if promConfig.MaxQPS > 0 {
+		retryTripper.limiter = rate.NewLimiter(rate.Limit(promConfig.MaxQPS), 1)
+	}
 	cli, err := api.NewClient(api.Config{
-		Address:      promConfig.URL,
-		RoundTripper: prom_config.NewHeadersRoundTripper(&headers, tripper),
+		Address: promConfig.URL,
+		// Tenant header set from the request context needs to override the static one, so it needs to be applied after it.
+		RoundTripper: prom_config.NewHeadersRoundTripper(&headers, &sourceTenantsRoundTripper{next: retryTripper}),
 	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to initialize prometheus client: %w", err)
 	}
 	v1cli := v1.NewAPI(cli)
 	var cacheInstance *cache
-	if promConfig.DisableCache {
-		cacheInstance = nil
-	} else {
-		cacheInstance = newCache(promConfig.CacheFile, promConfig.URL, promConfig.MaxCacheAge)
+	// Cached responses would be missing in the recorded fixtures and replayed responses do not need to be cached.
+	if !promConfig.DisableCache && promConfig.Mode == config.PrometheusModeLive {
+		cacheInstance = newCache(newCacheStore(promConfig), cacheKey(promConfig.URL, promConfig.QueryOffset, promConfig.QueryLookback, httpHeaders), promConfig.URL, promConfig.MaxCacheAge)
+	}
+	var inFlight chan struct{}
+	if promConfig.MaxInFlightQueries > 0 {
+		inFlight = make(chan struct{}, promConfig.MaxInFlightQueries)
 	}
 	promClient := Client{
-		apiClient:             v1cli,
-		httpHeaders:           &headers,
-		originalSourceTenants: originalSourceTenants,
-		httpHeadersMtx:        sync.Mutex{},
-		url:                   promConfig.URL,
-		timeout:               promConfig.Timeout,
-		queryOffset:           promConfig.QueryOffset,
-		queryLookback:         promConfig.QueryLookback,
-		cache:                 cacheInstance,
+		apiClient:     v1cli,
+		httpClient:    cli,
+		url:           promConfig.URL,
+		timeout:       promConfig.Timeout,
+		queryOffset:   promConfig.QueryOffset,
+		queryLookback: promConfig.QueryLookback,
+		cache:         cacheInstance,
+		inFlight:      inFlight,
+		stats:         stats,
 	}
 	return &promClient, nil
 }
 
+// Client is safe for concurrent use, the source tenants are passed with each request.
 type Client struct {
-	apiClient             v1.API
-	httpHeaders           *prom_config.Headers
-	originalSourceTenants []string
-	httpHeadersMtx        sync.Mutex
-	url                   string
-	timeout               time.Duration
-	queryOffset           time.Duration
-	queryLookback         time.Duration
-	cache                 *cache
+	apiClient v1.API
+	// httpClient is used for requests the v1 API does not fully support.
+	httpClient    api.Client
+	url           string
+	timeout       time.Duration
+	queryOffset   time.Duration
+	queryLookback time.Duration
+	cache         *cache
+	// inFlight limits the number of concurrent requests, nil means unlimited.
+	inFlight chan struct{}
+	stats    *requestStats
 }
 
-func (s *Client) SetSourceTenants(sourceTenants []string) {
-	s.httpHeadersMtx.Lock()
-	if len(sourceTenants) == 0 {
-		return
-	}
-	s.httpHeaders.Headers[user.OrgIDHeaderName] = prom_config.Header{Values: []string{sourceTenantsToHeader(sourceTenants)}}
-}
-
-func (s *Client) ClearSourceTenants() {
-	s.httpHeaders.Headers[user.OrgIDHeaderName] = prom_config.Header{Values: s.originalSourceTenants}
-	s.httpHeadersMtx.Unlock()
+// Stats returns the cumulative counters of requests sent by the client.
+func (s *Client) Stats() Stats {
+	return s.stats.snapshot()
 }
 
 func (s *Client) queryTimeRange() (start, end time.Time) {
@@ -138,19 +229,29 @@ func (s *Client) DumpCache() {
 	log.WithField("duration", time.Since(start)).Info("cache dumped")
 }
 
-func (s *Client) newContext() (context.Context, context.CancelFunc) {
-	ctx, cancel := context.WithCancel(context.Background())
+// newContext returns context of a request for the source tenants, it waits until the number of in-flight requests is below the limit.
+// The returned cancel function needs to be called to release the in-flight request slot.
+func (s *Client) newContext(sourceTenants []string) (context.Context, context.CancelFunc) {
+	if s.inFlight != nil {
+		s.inFlight <- struct{}{}
+	}
+	// The timeout starts after the request is allowed to be sent, so it is not consumed by the waiting.
+	ctx, cancel := context.WithCancel(withSourceTenants(context.Background(), sourceTenants))
 	if s.timeout != 0 {
 		ctx, cancel = context.WithTimeout(ctx, s.timeout)
 	}
-	return ctx, cancel
+	if s.inFlight == nil {
+		return ctx, cancel
+	}
+	return ctx, func() {
+		cancel()
+		<-s.inFlight
+	}
 }
 
 func (s *Client) SelectorMatch(selector string, sourceTenants []string) ([]model.LabelSet, error) {
-	ctx, cancel := s.newContext()
+	ctx, cancel := s.newContext(sourceTenants)
 	defer cancel()
-	s.SetSourceTenants(sourceTenants)
-	defer s.ClearSourceTenants()
 	start := time.Now()
 	queryStart, queryEnd := s.queryTimeRange()
 	result, warnings, err := s.apiClient.Series(ctx, []string{selector}, queryStart, queryEnd)
@@ -174,7 +275,9 @@ func (s *Client) SelectorMatchingSeries(selector string, sourceTenants []string)
 	var cache *cacheData
 	if s.cache != nil {
 		cache = s.cache.SourceTenantsData(sourceTenants)
-		if count, found := cache.MatchingSeriesForSelector(selector); found {
+		count, found := cache.MatchingSeriesForSelector(selector)
+		s.stats.countCacheLookup(found)
+		if found {
 			return count, nil
 		}
 	}
@@ -194,12 +297,11 @@ func (s *Client) Labels(sourceTenants []string) ([]string, error) {
 	if s.cache != nil {
 		cache = s.cache.SourceTenantsData(sourceTenants)
 		cachedLabels = cache.GetKnownLabels()
+		s.stats.countCacheLookup(len(cachedLabels) > 0)
 	}
 	if s.cache == nil || len(cachedLabels) == 0 {
-		ctx, cancel := s.newContext()
+		ctx, cancel := s.newContext(sourceTenants)
 		defer cancel()
-		s.SetSourceTenants(sourceTenants)
-		defer s.ClearSourceTenants()
 		start := time.Now()
 		queryStart, queryEnd := s.queryTimeRange()
 		result, warnings, err := s.apiClient.LabelNames(ctx, []string{}, queryStart, queryEnd)
@@ -226,10 +328,8 @@ func (s *Client) Labels(sourceTenants []string) ([]string, error) {
 }
 
 func (s *Client) Query(query string, sourceTenants []string) ([]*model.Sample, int, time.Duration, error) {
-	ctx, cancel := s.newContext()
+	ctx, cancel := s.newContext(sourceTenants)
 	defer cancel()
-	s.SetSourceTenants(sourceTenants)
-	defer s.ClearSourceTenants()
 	start := time.Now()
 	_, queryEnd := s.queryTimeRange()
 	result, warnings, err := s.apiClient.Query(ctx, query, queryEnd)
@@ -265,18 +365,283 @@ func (s *Client) Query(query string, sourceTenants []string) ([]*model.Sample, i
 	return nil, 0, 0, fmt.Errorf("unknown prometheus response type: %s", result)
 }
 
+// QueryStats are statistics of the instant query evaluation.
+type QueryStats struct {
+	// Series is the number of series in the result.
+	Series int `json:"series"`
+	// Duration is the duration of the whole request.
+	Duration time.Duration `json:"duration"`
+	// EvalDuration is the evaluation time reported by the Prometheus, zero if it is not reported.
+	EvalDuration time.Duration `json:"eval_duration,omitempty"`
+	// Samples is the total number of samples loaded by the query, zero if the Prometheus does not report it.
+	Samples int64 `json:"samples,omitempty"`
+	// PeakSamples is the maximum number of samples kept in memory at once during the evaluation, zero if not reported.
+	PeakSamples int64 `json:"peak_samples,omitempty"`
+}
+
+// queryStatsResponse is the response of the instant query with the `stats=all` param.
+type queryStatsResponse struct {
+	Status    string       `json:"status"`
+	ErrorType v1.ErrorType `json:"errorType"`
+	Error     string       `json:"error"`
+	Warnings  []string     `json:"warnings"`
+	Data      struct {
+		ResultType model.ValueType   `json:"resultType"`
+		Result     []json.RawMessage `json:"result"`
+		Stats      *struct {
+			Timings struct {
+				EvalTotalTime float64 `json:"evalTotalTime"`
+			} `json:"timings"`
+			Samples struct {
+				TotalQueryableSamples int64 `json:"totalQueryableSamples"`
+				PeakSamples           int64 `json:"peakSamples"`
+			} `json:"samples"`
+		} `json:"stats"`
+	} `json:"data"`
+}
+
+// queryError is the error the Prometheus returned for the evaluated query.
+type queryError struct {
+	errorType v1.ErrorType
+	message   string
+}
+
+func (e *queryError) Error() string {
+	return fmt.Sprintf("%s: %s", e.errorType, e.message)
+}
+
+// isCacheableQueryError returns true if the query itself is invalid or failed to evaluate, so it would fail the same way in the next run.
+// Transient errors like timeouts, network errors or exhausted retries are not cached.
+func isCacheableQueryError(err error) bool {
+	var qErr *queryError
+	return errors.As(err, &qErr) && (qErr.errorType == v1.ErrBadData || qErr.errorType == v1.ErrExec)
+}
+
+// queryWithStats evaluates the instant query requesting also its statistics, which are dropped by the v1 API client.
+func (s *Client) queryWithStats(query string, sourceTenants []string) (QueryStats, error) {
+	ctx, cancel := s.newContext(sourceTenants)
+	defer cancel()
+	start := time.Now()
+	_, queryEnd := s.queryTimeRange()
+	params := url.Values{
+		"query": []string{query},
+		"time":  []string{strconv.FormatFloat(float64(queryEnd.UnixMilli())/1000, 'f', -1, 64)},
+		"stats": []string{string(v1.AllStatsValue)},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.httpClient.URL("/api/v1/query", nil).String(), strings.NewReader(params.Encode()))
+	if err != nil {
+		return QueryStats{}, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, body, err := s.httpClient.Do(ctx, req)
+	if err != nil {
+		return QueryStats{}, fmt.Errorf("error querying prometheus: %w", err)
+	}
+	stats := QueryStats{Duration: time.Since(start)}
+	var response queryStatsResponse
+	if err := json.Unmarshal(body, &response); err != nil {
+		return QueryStats{}, fmt.Errorf("error querying prometheus: invalid response with status %s: %w", resp.Status, err)
+	}
+	if response.Status != "success" {
+		return QueryStats{}, fmt.Errorf("error querying prometheus: %w", &queryError{errorType: response.ErrorType, message: response.Error})
+	}
+	switch response.Data.ResultType {
+	case model.ValVector:
+		stats.Series = len(response.Data.Result)
+	case model.ValScalar:
+		stats.Series = 1
+	default:
+		return QueryStats{}, fmt.Errorf("unknown prometheus response type: %s", response.Data.ResultType)
+	}
+	if response.Data.Stats != nil {
+		stats.EvalDuration = time.Duration(response.Data.Stats.Timings.EvalTotalTime * float64(time.Second))
+		stats.Samples = response.Data.Stats.Samples.TotalQueryableSamples
+		stats.PeakSamples = response.Data.Stats.Samples.PeakSamples
+	}
+	log.WithFields(log.Fields{
+		"url":           s.url,
+		"query":         query,
+		"at":            queryEnd,
+		"sourceTenants": sourceTenants,
+		"duration":      stats.Duration,
+		"evalDuration":  stats.EvalDuration,
+		"series":        stats.Series,
+		"samples":       stats.Samples,
+	}).Debug("query prometheus with stats")
+	if len(response.Warnings) > 0 {
+		log.WithField("warnings", response.Warnings).Warn("Prometheus query returned warnings")
+	}
+	return stats, nil
+}
+
+// QueryStats evaluates the query and returns the number of the resulting series and the duration of the request.
+//
+// Deprecated: Use QueryStatistics, which returns also the evaluation time and the number of loaded samples.
 func (s *Client) QueryStats(query string, sourceTenants []string) (int, time.Duration, error) {
+	stats, err := s.QueryStatistics(query, sourceTenants)
+	return stats.Series, stats.Duration, err
+}
+
+// QueryStatistics evaluates the query and returns its statistics, successful queries and queries failed due to the query itself are cached.
+func (s *Client) QueryStatistics(query string, sourceTenants []string) (QueryStats, error) {
 	var cache *cacheData
 	if s.cache != nil {
 		cache = s.cache.SourceTenantsData(sourceTenants)
-		if stats, found := cache.GetQueryStats(query); found {
-			return stats.Series, stats.Duration, stats.Error
+		stats, found := cache.GetQueryStats(query)
+		s.stats.countCacheLookup(found)
+		if found {
+			if stats.Error != "" {
+				return stats.QueryStats, errors.New(stats.Error)
+			}
+			return stats.QueryStats, nil
 		}
 	}
-	_, series, duration, err := s.Query(query, sourceTenants)
+	stats, err := s.queryWithStats(query, sourceTenants)
+	if cache != nil && (err == nil || isCacheableQueryError(err)) {
+		cached := queryStats{QueryStats: stats}
+		if err != nil {
+			cached.Error = err.Error()
+		}
+		cache.SetQueryStats(query, cached)
+	}
+	return stats, err
+}
+
+// MetricsSeries returns the number of series of the metrics with the most series from the TSDB status.
+// Only the top metrics are returned, so the metrics missing in the result have fewer series than any of the returned ones.
+func (s *Client) MetricsSeries(sourceTenants []string) (map[string]int, error) {
+	var cache *cacheData
+	if s.cache != nil {
+		cache = s.cache.SourceTenantsData(sourceTenants)
+		cachedMetricsSeries := cache.GetMetricsSeries()
+		s.stats.countCacheLookup(cachedMetricsSeries != nil)
+		if cachedMetricsSeries != nil {
+			return cachedMetricsSeries, nil
+		}
+	}
+	ctx, cancel := s.newContext(sourceTenants)
+	defer cancel()
+	start := time.Now()
+	result, err := s.apiClient.TSDB(ctx, v1.WithLimit(tsdbStatusLimit))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query TSDB status: %w", err)
+	}
+	metricsSeries := make(map[string]int, len(result.SeriesCountByMetricName))
+	for _, stat := range result.SeriesCountByMetricName {
+		metricsSeries[stat.Name] = int(stat.Value)
+	}
+	log.WithFields(log.Fields{
+		"url":           s.url,
+		"sourceTenants": sourceTenants,
+		"duration":      time.Since(start),
+		"metrics":       len(metricsSeries),
+	}).Debug("loaded prometheus TSDB status")
 	if cache != nil {
-		stats := queryStats{Series: series, Duration: duration, Error: err}
-		cache.SetQueryStats(query, stats)
+		cache.SetMetricsSeries(metricsSeries)
+	}
+	return metricsSeries, nil
+}
+
+// ExpressionCardinality returns the number of series the expression evaluates to.
+// It is estimated by querying `count(<expr>)`, so the series themselves are not transferred.
+// Scalar expressions are not queried at all, since those always produce a single series when recorded.
+func (s *Client) ExpressionCardinality(expr string, sourceTenants []string) (int, error) {
+	parsedExpr, err := parser.ParseExpr(expr)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse expression `%s`: %w", expr, err)
+	}
+	switch parsedExpr.Type() {
+	case parser.ValueTypeScalar:
+		return 1, nil
+	case parser.ValueTypeString:
+		return 0, fmt.Errorf("expression `%s` evaluates to a string, not series", expr)
+	}
+	var cache *cacheData
+	if s.cache != nil {
+		cache = s.cache.SourceTenantsData(sourceTenants)
+		cardinality, found := cache.GetExpressionCardinality(expr)
+		s.stats.countCacheLookup(found)
+		if found {
+			return cardinality, nil
+		}
+	}
+	samples, _, _, err := s.Query(fmt.Sprintf("count(%s)", expr), sourceTenants)
+	if err != nil {
+		return 0, err
+	}
+	cardinality := 0
+	if len(samples) > 0 {
+		cardinality = int(samples[0].Value)
+	}
+	if cache != nil {
+		cache.SetExpressionCardinality(expr, cardinality)
+	}
+	return cardinality, nil
+}
+
+// QueryRange evaluates the query over the window ending at the current time minus the query offset.
+// Windows with more than maxRangeQueryPoints steps are split into multiple range queries.
+// The result is not cached, since the samples of the whole window would make the cache too large.
+func (s *Client) QueryRange(query string, sourceTenants []string, window, step time.Duration) (model.Matrix, error) {
+	_, queryEnd := s.queryTimeRange()
+	var matrix model.Matrix
+	seriesByFingerprint := map[model.Fingerprint]*model.SampleStream{}
+	for chunkStart := queryEnd.Add(-window); !chunkStart.After(queryEnd); {
+		chunkEnd := chunkStart.Add(step * (maxRangeQueryPoints - 1))
+		if chunkEnd.After(queryEnd) {
+			chunkEnd = queryEnd
+		}
+		chunk, err := s.queryRange(query, sourceTenants, v1.Range{Start: chunkStart, End: chunkEnd, Step: step})
+		if err != nil {
+			return nil, err
+		}
+		for _, series := range chunk {
+			fingerprint := series.Metric.Fingerprint()
+			if existing, ok := seriesByFingerprint[fingerprint]; ok {
+				// Only samples after the previous chunk are added, replayed fixtures are the same for all the chunks.
+				last := model.Earliest
+				if len(existing.Values) > 0 {
+					last = existing.Values[len(existing.Values)-1].Timestamp
+				}
+				for _, sample := range series.Values {
+					if sample.Timestamp.After(last) {
+						existing.Values = append(existing.Values, sample)
+					}
+				}
+				continue
+			}
+			seriesByFingerprint[fingerprint] = series
+			matrix = append(matrix, series)
+		}
+		chunkStart = chunkEnd.Add(step)
+	}
+	return matrix, nil
+}
+
+func (s *Client) queryRange(query string, sourceTenants []string, queryRange v1.Range) (model.Matrix, error) {
+	ctx, cancel := s.newContext(sourceTenants)
+	defer cancel()
+	start := time.Now()
+	result, warnings, err := s.apiClient.QueryRange(ctx, query, queryRange)
+	if err != nil {
+		return nil, fmt.Errorf("error querying prometheus: %w", err)
+	}
+	log.WithFields(log.Fields{
+		"url":           s.url,
+		"query":         query,
+		"start":         queryRange.Start,
+		"end":           queryRange.End,
+		"step":          queryRange.Step,
+		"sourceTenants": sourceTenants,
+		"duration":      time.Since(start),
+		"resultType":    result.Type().String(),
+	}).Debug("range query prometheus")
+	if len(warnings) > 0 {
+		log.WithField("warnings", warnings).Warn("Prometheus query returned warnings")
+	}
+	matrix, ok := result.(model.Matrix)
+	if !ok {
+		return nil, fmt.Errorf("unexpected prometheus range query response type: %s", result.Type())
 	}
-	return series, duration, err
+	return matrix, nil
 }
@@ -0,0 +1,228 @@
+package prometheus
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/fusakla/promruval/v3/pkg/config"
+	"github.com/grafana/dskit/user"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClientConcurrentSourceTenants(t *testing.T) {
+	var inFlight, maxInFlight atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		current := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for {
+			observed := maxInFlight.Load()
+			if current <= observed || maxInFlight.CompareAndSwap(observed, current) {
+				break
+			}
+		}
+		time.Sleep(50 * time.Millisecond)
+		_ = r.ParseForm()
+		// The query is the expected tenant header, so the response tells whether the tenant matched.
+		value := 0
+		if r.Header.Get(user.OrgIDHeaderName) == r.Form.Get("query") {
+			value = 1
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = fmt.Fprintf(w, `{"status":"success","data":{"resultType":"scalar","result":[0,"%d"]}}`, value)
+	}))
+	defer server.Close()
+
+	client, err := NewClient(config.PrometheusConfig{
+		URL:                server.URL,
+		DisableCache:       true,
+		MaxInFlightQueries: 3,
+		HTTPHeaders:        map[string]string{user.OrgIDHeaderName: "default"},
+	})
+	require.NoError(t, err)
+
+	tests := []struct {
+		sourceTenants  []string
+		expectedHeader string
+	}{
+		{sourceTenants: nil, expectedHeader: "default"},
+		{sourceTenants: []string{"team-a"}, expectedHeader: "team-a"},
+		{sourceTenants: []string{"team-c", "team-b"}, expectedHeader: "team-b|team-c"},
+	}
+	var wg sync.WaitGroup
+	for i := range 12 {
+		tt := tests[i%len(tests)]
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			samples, _, _, err := client.Query(tt.expectedHeader, tt.sourceTenants)
+			if assert.NoError(t, err) {
+				assert.Equal(t, 1.0, float64(samples[0].Value), "unexpected tenant header for source tenants %v", tt.sourceTenants)
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int32(3), maxInFlight.Load())
+}
+
+func TestClientCardinality(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		_ = r.ParseForm()
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/api/v1/status/tsdb":
+			assert.Equal(t, "1000", r.Form.Get("limit"))
+			_, _ = fmt.Fprint(w, `{"status":"success","data":{"seriesCountByMetricName":[{"name":"up","value":42}]}}`)
+		case "/api/v1/query":
+			assert.Equal(t, "count(sum by (job) (up))", r.Form.Get("query"))
+			_, _ = fmt.Fprint(w, `{"status":"success","data":{"resultType":"vector","result":[{"metric":{},"value":[0,"7"]}]}}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	client, err := NewClient(config.PrometheusConfig{URL: server.URL, CacheFile: filepath.Join(t.TempDir(), "cache.json"), MaxCacheAge: time.Hour})
+	require.NoError(t, err)
+	for range 2 {
+		metricsSeries, err := client.MetricsSeries(nil)
+		require.NoError(t, err)
+		assert.Equal(t, map[string]int{"up": 42}, metricsSeries)
+		cardinality, err := client.ExpressionCardinality("sum by (job) (up)", nil)
+		require.NoError(t, err)
+		assert.Equal(t, 7, cardinality)
+	}
+	assert.Equal(t, int32(2), requests.Load())
+	assert.Equal(t, 2, client.Stats().CacheHits)
+
+	// Scalars cannot be wrapped in count(), those are recorded as a single series.
+	cardinality, err := client.ExpressionCardinality("scalar(up) * 2", nil)
+	require.NoError(t, err)
+	assert.Equal(t, 1, cardinality)
+	_, err = client.ExpressionCardinality(`"foo"`, nil)
+	assert.ErrorContains(t, err, "evaluates to a string")
+	_, err = client.ExpressionCardinality("sum(", nil)
+	assert.ErrorContains(t, err, "failed to parse expression")
+	assert.Equal(t, int32(2), requests.Load())
+}
+
+func TestClientQueryRangeChunks(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		_ = r.ParseForm()
+		assert.Equal(t, "/api/v1/query_range", r.URL.Path)
+		start, err := strconv.ParseFloat(r.Form.Get("start"), 64)
+		require.NoError(t, err)
+		end, err := strconv.ParseFloat(r.Form.Get("end"), 64)
+		require.NoError(t, err)
+		assert.LessOrEqual(t, int(end-start)/30+1, maxRangeQueryPoints)
+		// Single series with the first and last sample of the range.
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = fmt.Fprintf(w, `{"status":"success","data":{"resultType":"matrix","result":[{"metric":{"job":"foo"},"values":[[%f,"1"],[%f,"1"]]}]}}`, start, end)
+	}))
+	defer server.Close()
+
+	client, err := NewClient(config.PrometheusConfig{URL: server.URL, DisableCache: true})
+	require.NoError(t, err)
+	// A week with the 30s step has 20161 points, which is split into two queries.
+	matrix, err := client.QueryRange("up", nil, 7*24*time.Hour, 30*time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), requests.Load())
+	require.Len(t, matrix, 1)
+	require.Len(t, matrix[0].Values, 4)
+	assert.Equal(t, 30*time.Second, matrix[0].Values[2].Timestamp.Time().Sub(matrix[0].Values[1].Timestamp.Time()))
+	assert.Equal(t, 7*24*time.Hour, matrix[0].Values[3].Timestamp.Time().Sub(matrix[0].Values[0].Timestamp.Time()))
+}
+
+func TestClientQueryStats(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		assert.Equal(t, "/prometheus/api/v1/query", r.URL.Path)
+		assert.Equal(t, "all", r.Form.Get("stats"))
+		w.Header().Set("Content-Type", "application/json")
+		switch r.Form.Get("query") {
+		case "up":
+			_, _ = fmt.Fprint(w, `{"status":"success","data":{"resultType":"vector","result":[{"metric":{},"value":[0,"1"]},{"metric":{},"value":[0,"1"]}],"stats":{"timings":{"evalTotalTime":1.5},"samples":{"totalQueryableSamples":120,"peakSamples":60}}}}`)
+		case "1":
+			_, _ = fmt.Fprint(w, `{"status":"success","data":{"resultType":"scalar","result":[0,"1"]}}`)
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = fmt.Fprint(w, `{"status":"error","errorType":"bad_data","error":"parse error"}`)
+		}
+	}))
+	defer server.Close()
+
+	client, err := NewClient(config.PrometheusConfig{URL: server.URL + "/prometheus", DisableCache: true})
+	require.NoError(t, err)
+	stats, err := client.QueryStatistics("up", nil)
+	require.NoError(t, err)
+	assert.Equal(t, 2, stats.Series)
+	assert.Equal(t, 1500*time.Millisecond, stats.EvalDuration)
+	assert.Equal(t, int64(120), stats.Samples)
+	assert.Equal(t, int64(60), stats.PeakSamples)
+	assert.Positive(t, stats.Duration)
+	series, duration, err := client.QueryStats("up", nil)
+	require.NoError(t, err)
+	assert.Equal(t, 2, series)
+	assert.Positive(t, duration)
+
+	stats, err = client.QueryStatistics("1", nil)
+	require.NoError(t, err)
+	assert.Equal(t, 1, stats.Series)
+	assert.Zero(t, stats.Samples)
+
+	_, err = client.QueryStatistics("foo(", nil)
+	assert.ErrorContains(t, err, "bad_data: parse error")
+}
+
+func TestClientQueryStatsErrorsCaching(t *testing.T) {
+	tests := []struct {
+		name           string
+		status         int
+		body           string
+		expectedCached bool
+	}{
+		{name: "badData", status: http.StatusBadRequest, body: `{"status":"error","errorType":"bad_data","error":"parse error"}`, expectedCached: true},
+		{name: "execution", status: http.StatusUnprocessableEntity, body: `{"status":"error","errorType":"execution","error":"many-to-many matching not allowed"}`, expectedCached: true},
+		{name: "timeout", status: http.StatusServiceUnavailable, body: `{"status":"error","errorType":"timeout","error":"query timed out"}`},
+		{name: "unavailable", status: http.StatusServiceUnavailable, body: `upstream connect error`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requests atomic.Int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				requests.Add(1)
+				w.WriteHeader(tt.status)
+				_, _ = fmt.Fprint(w, tt.body)
+			}))
+			defer server.Close()
+
+			promConfig := config.PrometheusConfig{URL: server.URL, CacheFile: filepath.Join(t.TempDir(), "cache.json"), MaxCacheAge: time.Hour}
+			client, err := NewClient(promConfig)
+			require.NoError(t, err)
+			_, err = client.QueryStatistics("foo", nil)
+			require.Error(t, err)
+			client.DumpCache()
+
+			client, err = NewClient(promConfig)
+			require.NoError(t, err)
+			_, err = client.QueryStatistics("foo", nil)
+			require.Error(t, err)
+			expectedRequests := int32(2)
+			if tt.expectedCached {
+				expectedRequests = 1
+			}
+			assert.Equal(t, expectedRequests, requests.Load())
+		})
+	}
+}
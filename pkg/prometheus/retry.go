@@ -0,0 +1,173 @@
+package prometheus
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/time/rate"
+)
+
+// Stats are counters of the requests sent to the Prometheus and of the cache lookups.
+type Stats struct {
+	// Requests is the number of logical requests, not counting the retries.
+	Requests int
+	// Retries is the number of retried requests attempts.
+	Retries int
+	// Throttled is the number of responses with the 429 Too Many Requests status.
+	Throttled int
+	// Failed is the number of requests which failed even after all the retries.
+	Failed int
+	// CacheHits and CacheMisses count lookups of the responses in the cache.
+	CacheHits   int
+	CacheMisses int
+}
+
+// Add returns sum of the stats.
+func (s Stats) Add(other Stats) Stats {
+	return Stats{
+		Requests:    s.Requests + other.Requests,
+		Retries:     s.Retries + other.Retries,
+		Throttled:   s.Throttled + other.Throttled,
+		Failed:      s.Failed + other.Failed,
+		CacheHits:   s.CacheHits + other.CacheHits,
+		CacheMisses: s.CacheMisses + other.CacheMisses,
+	}
+}
+
+// Sub returns difference of the stats, useful to get stats of a single validation run from the cumulative client stats.
+func (s Stats) Sub(other Stats) Stats {
+	return Stats{
+		Requests:    s.Requests - other.Requests,
+		Retries:     s.Retries - other.Retries,
+		Throttled:   s.Throttled - other.Throttled,
+		Failed:      s.Failed - other.Failed,
+		CacheHits:   s.CacheHits - other.CacheHits,
+		CacheMisses: s.CacheMisses - other.CacheMisses,
+	}
+}
+
+type requestStats struct {
+	requests, retries, throttled, failed atomic.Int64
+	cacheHits, cacheMisses               atomic.Int64
+}
+
+func (s *requestStats) countCacheLookup(hit bool) {
+	if hit {
+		s.cacheHits.Add(1)
+	} else {
+		s.cacheMisses.Add(1)
+	}
+}
+
+func (s *requestStats) snapshot() Stats {
+	return Stats{
+		Requests:    int(s.requests.Load()),
+		Retries:     int(s.retries.Load()),
+		Throttled:   int(s.throttled.Load()),
+		Failed:      int(s.failed.Load()),
+		CacheHits:   int(s.cacheHits.Load()),
+		CacheMisses: int(s.cacheMisses.Load()),
+	}
+}
+
+// retryRoundTripper retries requests failed with a transient error using exponential backoff and limits the rate of requests.
+type retryRoundTripper struct {
+	next       http.RoundTripper
+	maxRetries int
+	minBackoff time.Duration
+	maxBackoff time.Duration
+	// limiter is nil if the rate is not limited.
+	limiter *rate.Limiter
+	stats   *requestStats
+}
+
+func isRetryableStatus(status int) bool {
+	return status == http.StatusTooManyRequests || status == http.StatusBadGateway || status == http.StatusServiceUnavailable || status == http.StatusGatewayTimeout
+}
+
+func isRetryableError(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout() || errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, io.EOF)
+}
+
+// backoff returns the exponential backoff with jitter for the given attempt, Retry-After header of the response is respected up to the max backoff.
+func (rt *retryRoundTripper) backoff(attempt int, resp *http.Response) time.Duration {
+	// Doubling stops at the max backoff, so the duration cannot overflow for any number of attempts.
+	backoff := rt.minBackoff
+	for range attempt {
+		if backoff >= rt.maxBackoff {
+			break
+		}
+		backoff *= 2
+	}
+	backoff = min(backoff, rt.maxBackoff)
+	backoff = backoff/2 + rand.N(backoff/2+1)
+	if resp != nil {
+		if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+			backoff = max(backoff, min(time.Duration(seconds)*time.Second, rt.maxBackoff))
+		}
+	}
+	return backoff
+}
+
+func (rt *retryRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	rt.stats.requests.Add(1)
+	for attempt := 0; ; attempt++ {
+		if rt.limiter != nil {
+			if err := rt.limiter.Wait(ctx); err != nil {
+				rt.stats.failed.Add(1)
+				return nil, err
+			}
+		}
+		attemptReq := req
+		if attempt > 0 && req.Body != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				rt.stats.failed.Add(1)
+				return nil, err
+			}
+			attemptReq = req.Clone(ctx)
+			attemptReq.Body = body
+		}
+		resp, err := rt.next.RoundTrip(attemptReq)
+		if resp != nil && resp.StatusCode == http.StatusTooManyRequests {
+			rt.stats.throttled.Add(1)
+		}
+		retryable := err == nil && isRetryableStatus(resp.StatusCode) || err != nil && isRetryableError(ctx, err)
+		// Requests with body which cannot be recreated cannot be retried.
+		if !retryable || attempt >= rt.maxRetries || req.Body != nil && req.GetBody == nil {
+			if err != nil || resp.StatusCode >= http.StatusBadRequest {
+				rt.stats.failed.Add(1)
+			}
+			return resp, err
+		}
+		backoff := rt.backoff(attempt, resp)
+		logFields := log.Fields{"url": req.URL.Redacted(), "attempt": attempt + 1, "backoff": backoff}
+		if err != nil {
+			log.WithFields(logFields).WithError(err).Debug("retrying failed prometheus request")
+		} else {
+			log.WithFields(logFields).WithField("status", resp.StatusCode).Debug("retrying failed prometheus request")
+			_, _ = io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+		rt.stats.retries.Add(1)
+		select {
+		case <-ctx.Done():
+			rt.stats.failed.Add(1)
+			return nil, ctx.Err()
+		case <-time.After(backoff):
+		}
+	}
+}
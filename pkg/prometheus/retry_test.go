@@ -0,0 +1,108 @@
+package prometheus
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/fusakla/promruval/v3/pkg/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClientRetries(t *testing.T) {
+	tests := []struct {
+		name          string
+		statuses      []int
+		maxRetries    int
+		expectedError bool
+		expectedStats Stats
+	}{
+		{name: "success", statuses: []int{http.StatusOK}, maxRetries: 3, expectedStats: Stats{Requests: 1}},
+		{name: "retried throttling", statuses: []int{http.StatusTooManyRequests, http.StatusServiceUnavailable, http.StatusOK}, maxRetries: 3, expectedStats: Stats{Requests: 1, Retries: 2, Throttled: 1}},
+		{name: "retries exhausted", statuses: []int{http.StatusBadGateway, http.StatusGatewayTimeout, http.StatusServiceUnavailable}, maxRetries: 2, expectedError: true, expectedStats: Stats{Requests: 1, Retries: 2, Failed: 1}},
+		{name: "retries disabled", statuses: []int{http.StatusTooManyRequests}, maxRetries: 0, expectedError: true, expectedStats: Stats{Requests: 1, Throttled: 1, Failed: 1}},
+		{name: "not retryable status", statuses: []int{http.StatusBadRequest, http.StatusOK}, maxRetries: 3, expectedError: true, expectedStats: Stats{Requests: 1, Failed: 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var attempt atomic.Int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				status := tt.statuses[min(int(attempt.Add(1))-1, len(tt.statuses)-1)]
+				w.Header().Set("Content-Type", "application/json")
+				if status == http.StatusTooManyRequests {
+					w.Header().Set("Retry-After", "0")
+				}
+				w.WriteHeader(status)
+				if status == http.StatusOK {
+					_, _ = w.Write([]byte(`{"status":"success","data":{"resultType":"vector","result":[]}}`))
+					return
+				}
+				_, _ = w.Write([]byte(`{"status":"error","errorType":"unavailable","error":"try again"}`))
+			}))
+			defer server.Close()
+
+			client, err := NewClient(config.PrometheusConfig{
+				URL:             server.URL,
+				DisableCache:    true,
+				MaxRetries:      tt.maxRetries,
+				RetryMinBackoff: time.Millisecond,
+				RetryMaxBackoff: 5 * time.Millisecond,
+			})
+			require.NoError(t, err)
+			_, _, _, err = client.Query("up", nil)
+			if tt.expectedError {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tt.expectedStats, client.Stats())
+		})
+	}
+}
+
+func TestClientMaxQPS(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"status":"success","data":{"resultType":"vector","result":[]}}`))
+	}))
+	defer server.Close()
+
+	client, err := NewClient(config.PrometheusConfig{
+		URL:          server.URL,
+		DisableCache: true,
+		MaxQPS:       20,
+	})
+	require.NoError(t, err)
+	start := time.Now()
+	for range 5 {
+		_, _, _, err := client.Query("up", nil)
+		require.NoError(t, err)
+	}
+	// The first request is sent immediately, each of the following ones waits for 50ms.
+	assert.GreaterOrEqual(t, time.Since(start), 190*time.Millisecond)
+}
+
+func TestRetryBackoff(t *testing.T) {
+	rt := &retryRoundTripper{minBackoff: 500 * time.Millisecond, maxBackoff: 10 * time.Second}
+	tests := []struct {
+		name        string
+		attempt     int
+		minExpected time.Duration
+		maxExpected time.Duration
+	}{
+		{name: "first", attempt: 0, minExpected: 250 * time.Millisecond, maxExpected: 500 * time.Millisecond},
+		{name: "doubled", attempt: 2, minExpected: time.Second, maxExpected: 2 * time.Second},
+		{name: "capped", attempt: 10, minExpected: 5 * time.Second, maxExpected: 10 * time.Second},
+		{name: "largeAttempt", attempt: 100, minExpected: 5 * time.Second, maxExpected: 10 * time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			backoff := rt.backoff(tt.attempt, nil)
+			assert.GreaterOrEqual(t, backoff, tt.minExpected)
+			assert.LessOrEqual(t, backoff, tt.maxExpected)
+		})
+	}
+}
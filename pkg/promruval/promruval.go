@@ -0,0 +1,142 @@
+// Package promruval exposes the rules validation as a library so it can be embedded in other Go programs.
+//
+// Example:
+//
+//	cfg, err := promruval.LoadConfig("validation.yaml")
+//	if err != nil {
+//		return err
+//	}
+//	engine, err := promruval.NewEngine(cfg, promruval.Options{Formats: promruval.Formats{Mimir: true}})
+//	if err != nil {
+//		return err
+//	}
+//	defer engine.Close()
+//	validationReport, err := engine.ValidateFiles(ctx, []string{"rules/*.yaml"})
+package promruval
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/fusakla/promruval/v3/pkg/config"
+	"github.com/fusakla/promruval/v3/pkg/extractvalidators"
+	"github.com/fusakla/promruval/v3/pkg/prometheus"
+	"github.com/fusakla/promruval/v3/pkg/report"
+	"github.com/fusakla/promruval/v3/pkg/unmarshaler"
+	"github.com/fusakla/promruval/v3/pkg/validate"
+	"github.com/fusakla/promruval/v3/pkg/validationrule"
+	"github.com/prometheus/prometheus/model/rulefmt"
+	log "github.com/sirupsen/logrus"
+)
+
+// InMemoryGroupsSourceName is the file name used in the report for groups validated using Engine.ValidateGroups.
+const InMemoryGroupsSourceName = "<in-memory>"
+
+type (
+	// RuleGroup is a rule group to be validated, use NewRule to create its rules.
+	RuleGroup = unmarshaler.RuleGroup
+	// Rule is a single rule of the RuleGroup.
+	Rule = unmarshaler.RuleWithComment
+	// Formats configures which extensions of the Prometheus rules format are supported.
+	Formats = unmarshaler.Formats
+)
+
+// NewRule creates a rule to be used in the RuleGroup.
+func NewRule(rule rulefmt.Rule) Rule {
+	return unmarshaler.NewRuleWithComment(rule)
+}
+
+// LoadConfig loads the validation config files, additional files are composed the same way as when passing the `--config-file` flag multiple times.
+func LoadConfig(paths ...string) (*config.Config, error) {
+	return config.LoadConfiguration(paths)
+}
+
+// Options of the Engine.
+type Options struct {
+	// DisabledRules are names of the validation rules to be skipped.
+	DisabledRules []string
+	// EnabledRules if set, only validation rules with these names are used.
+	EnabledRules []string
+	// Formats configures which extensions of the Prometheus rules format are supported.
+	Formats Formats
+	// DisableParallelization runs all the validations sequentially.
+	DisableParallelization bool
+	// PrometheusClient is used by the validators using live Prometheus data instead of the default one created from the config.
+	// The named prometheusBackends from the config are still used for the groups and rules matching their selectors.
+	PrometheusClient *prometheus.Client
+}
+
+// Engine validates rule files or groups using the validation rules loaded from the config.
+// It is safe for concurrent use.
+type Engine struct {
+	config                    *config.Config
+	options                   Options
+	validationRules           []*validationrule.ValidationRule
+	prometheusClient          *prometheus.Client
+	prometheusClients         *prometheus.Backends
+	ownsPrometheusClient      bool
+	excludeAnnotationName     string
+	disableValidationsComment string
+}
+
+// NewEngine creates the validators defined in the config, fails if the config is invalid.
+func NewEngine(cfg *config.Config, opts Options) (*Engine, error) {
+	var err error
+	e := &Engine{
+		config:  cfg,
+		options: opts,
+	}
+	if opts.PrometheusClient != nil {
+		e.prometheusClient = opts.PrometheusClient
+	} else if cfg.Prometheus.URL != "" {
+		e.prometheusClient, err = prometheus.NewClient(cfg.Prometheus)
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize prometheus client: %w", err)
+		}
+		e.ownsPrometheusClient = true
+	}
+	e.prometheusClients, err = prometheus.NewBackends(e.prometheusClient, cfg.PrometheusBackends)
+	if err != nil {
+		return nil, err
+	}
+	e.validationRules, err = extractvalidators.ValidationRulesFromConfig(cfg, opts.DisabledRules, opts.EnabledRules)
+	if err != nil {
+		return nil, err
+	}
+	e.excludeAnnotationName, e.disableValidationsComment = validate.ExcludeAnnotationAndDisableComment(cfg)
+	return e, nil
+}
+
+// ValidationRules returns the validation rules used by the engine.
+func (e *Engine) ValidationRules() []*validationrule.ValidationRule {
+	return e.validationRules
+}
+
+// ValidateFiles validates the rule files, paths can contain double star globs.
+func (e *Engine) ValidateFiles(ctx context.Context, paths []string) (*report.ValidationReport, error) {
+	files, err := validate.ExpandFilePaths(paths)
+	if err != nil {
+		return nil, err
+	}
+	validationReport := validate.ValidateFiles(ctx, files, e.validationRules, e.excludeAnnotationName, e.disableValidationsComment, e.prometheusClients, e.options.Formats, e.config.Jsonnet, e.options.DisableParallelization)
+	return validationReport, ctx.Err()
+}
+
+// ValidateGroups validates rule groups which were not loaded from a file, those are reported as a single file named InMemoryGroupsSourceName.
+// The name is also used to match the validation rules includePaths and excludePaths.
+func (e *Engine) ValidateGroups(ctx context.Context, groups []RuleGroup) (*report.ValidationReport, error) {
+	validationReport := validate.Groups(ctx, InMemoryGroupsSourceName, groups, e.validationRules, e.excludeAnnotationName, e.disableValidationsComment, e.prometheusClients, e.options.DisableParallelization)
+	return validationReport, ctx.Err()
+}
+
+// Close persists the Prometheus query cache of the named backends and of the default client if the engine created it.
+// It also releases the resources held by the validators, such as the WASM plugin runtimes, so the engine must not be used afterwards.
+func (e *Engine) Close() {
+	if e.ownsPrometheusClient {
+		e.prometheusClient.DumpCache()
+	}
+	e.prometheusClients.DumpCache()
+	if err := validationrule.CloseAll(e.validationRules); err != nil {
+		log.WithError(err).Warn("failed to close the validation rules")
+	}
+}
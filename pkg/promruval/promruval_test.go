@@ -0,0 +1,119 @@
+package promruval
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/prometheus/prometheus/model/rulefmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testConfig = `
+validationRules:
+  - name: severity
+    scope: All rules
+    validations:
+      - type: hasLabels
+        params:
+          labels: ["severity"]
+`
+
+const testRules = `
+groups:
+  - name: test
+    source_tenants: ["foo"]
+    rules:
+      - alert: Foo
+        expr: up == 0
+        labels:
+          severity: critical
+`
+
+func newTestEngine(t *testing.T, opts Options) *Engine {
+	t.Helper()
+	configFile := filepath.Join(t.TempDir(), "validation.yaml")
+	require.NoError(t, os.WriteFile(configFile, []byte(testConfig), 0o600))
+	cfg, err := LoadConfig(configFile)
+	require.NoError(t, err)
+	engine, err := NewEngine(cfg, opts)
+	require.NoError(t, err)
+	t.Cleanup(engine.Close)
+	return engine
+}
+
+func TestEngineValidateGroups(t *testing.T) {
+	engine := newTestEngine(t, Options{})
+	validationReport, err := engine.ValidateGroups(context.Background(), []RuleGroup{
+		{
+			Name: "valid",
+			Rules: []Rule{
+				NewRule(rulefmt.Rule{Alert: "Foo", Expr: "up == 0", Labels: map[string]string{"severity": "critical"}}),
+			},
+		},
+		{
+			Name: "invalid",
+			Rules: []Rule{
+				NewRule(rulefmt.Rule{Record: "foo", Expr: "up"}),
+			},
+		},
+	})
+	require.NoError(t, err)
+	assert.True(t, validationReport.Failed)
+	assert.Equal(t, 2, validationReport.GroupsCount)
+	assert.Equal(t, 2, validationReport.RulesCount)
+	require.Len(t, validationReport.FilesReports, 1)
+	assert.Equal(t, InMemoryGroupsSourceName, validationReport.FilesReports[0].Name)
+}
+
+func TestEngineValidateGroupsPaths(t *testing.T) {
+	configFile := filepath.Join(t.TempDir(), "validation.yaml")
+	require.NoError(t, os.WriteFile(configFile, []byte(`
+validationRules:
+  - name: severity
+    scope: All rules
+    includePaths: ["rules/**"]
+    validations:
+      - type: hasLabels
+        params:
+          labels: ["severity"]
+`), 0o600))
+	cfg, err := LoadConfig(configFile)
+	require.NoError(t, err)
+	engine, err := NewEngine(cfg, Options{})
+	require.NoError(t, err)
+	t.Cleanup(engine.Close)
+
+	validationReport, err := engine.ValidateGroups(context.Background(), []RuleGroup{
+		{
+			Name: "invalid",
+			Rules: []Rule{
+				NewRule(rulefmt.Rule{Record: "foo", Expr: "up"}),
+			},
+		},
+	})
+	require.NoError(t, err)
+	assert.False(t, validationReport.Failed, "the validation rule does not apply to the %s", InMemoryGroupsSourceName)
+	assert.Equal(t, 1, validationReport.RulesCount)
+}
+
+func TestEngineValidateFilesFormats(t *testing.T) {
+	rulesFile := filepath.Join(t.TempDir(), "rules.yaml")
+	require.NoError(t, os.WriteFile(rulesFile, []byte(testRules), 0o600))
+
+	validationReport, err := newTestEngine(t, Options{}).ValidateFiles(context.Background(), []string{rulesFile})
+	require.NoError(t, err)
+	assert.True(t, validationReport.Failed, "source_tenants should not be allowed without Mimir support")
+
+	validationReport, err = newTestEngine(t, Options{Formats: Formats{Mimir: true}}).ValidateFiles(context.Background(), []string{rulesFile})
+	require.NoError(t, err)
+	assert.False(t, validationReport.Failed)
+	assert.Equal(t, 1, validationReport.RulesCount)
+}
+
+func TestEngineDisabledRules(t *testing.T) {
+	engine := newTestEngine(t, Options{DisabledRules: []string{"severity"}})
+	assert.Empty(t, engine.ValidationRules())
+}
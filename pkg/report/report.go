@@ -17,8 +17,10 @@ import (
 type ValidationRule interface {
 	Name() string
 	Scope() config.ValidationScope
-	ValidationTexts() []string
-	OnlyIfValidationTexts() []string
+	ValidationTexts() []ValidationText
+	OnlyIfValidationTexts() []ValidationText
+	IncludePaths() []string
+	ExcludePaths() []string
 	json.Marshaler
 	yaml.Marshaler
 }
@@ -76,6 +78,9 @@ type ValidationReport struct {
 	RulesCount         int `json:"rules_count" yaml:"rules_count"`
 	RulesExcludedCount int `json:"excluded_rules_count" yaml:"excluded_rules_count"`
 
+	// PrometheusStats are set only if any request was sent to the Prometheus.
+	PrometheusStats *PrometheusStats `json:"prometheus_stats,omitempty" yaml:"prometheus_stats,omitempty"`
+
 	ValidationRules []ValidationRule `json:"validation_rules" yaml:"validation_rules"`
 
 	FilesReports []*FileReport `json:"files_reports" yaml:"files_reports"`
@@ -83,6 +88,17 @@ type ValidationReport struct {
 	mu sync.Mutex `json:"-" yaml:"-"`
 }
 
+// PrometheusStats are counters of the requests sent to the Prometheus and the cache lookups during the validation.
+type PrometheusStats struct {
+	Requests  int `json:"requests" yaml:"requests"`
+	Retries   int `json:"retries" yaml:"retries"`
+	Throttled int `json:"throttled" yaml:"throttled"`
+	Failed    int `json:"failed" yaml:"failed"`
+	// CacheHits and CacheMisses count lookups of the responses in the query cache.
+	CacheHits   int `json:"cache_hits" yaml:"cache_hits"`
+	CacheMisses int `json:"cache_misses" yaml:"cache_misses"`
+}
+
 func (r *ValidationReport) NewFileReport(name string) *FileReport {
 	newReport := FileReport{
 		Name:         name,
@@ -253,6 +269,10 @@ func (r *ValidationReport) AsText(indentationStep int, color bool) (string, erro
 	output.AddLine(renderStatistic("Files", r.FilesCount, r.FilesExcludedCount))
 	output.AddLine(renderStatistic("Groups", r.GroupsCount, r.GroupsExcludedCount))
 	output.AddLine(renderStatistic("Rules", r.RulesCount, r.RulesExcludedCount))
+	if r.PrometheusStats != nil {
+		output.AddLine(fmt.Sprintf("Prometheus requests: %d with %d retries, %d throttled and %d failed", r.PrometheusStats.Requests, r.PrometheusStats.Retries, r.PrometheusStats.Throttled, r.PrometheusStats.Failed))
+		output.AddLine(fmt.Sprintf("Prometheus cache: %d hits and %d misses", r.PrometheusStats.CacheHits, r.PrometheusStats.CacheMisses))
+	}
 	return output.Text(), nil
 }
 
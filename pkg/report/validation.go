@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"html/template"
 	"regexp"
+	"strings"
 )
 
 var htmlTemplate = `
@@ -12,18 +13,23 @@ var htmlTemplate = `
 {{- range .Rules }}
   <br/>
   <h2><a href="#{{.Name}}">{{.Name}}</a></h2>
+	  {{- if .IncludePaths }}
+	  <p>Only for files matching: {{ .IncludePaths | backticksToCodeTag | escape }}</p>
+	  {{- end }}
+	  {{- if .ExcludePaths }}
+	  <p>Except files matching: {{ .ExcludePaths | backticksToCodeTag | escape }}</p>
+	  {{- end }}
 	  {{- if .OnlyIf }}
   	  <h4>Only if ALL the following conditions are met:</h4>
-	  <ul>
-	  {{- range .OnlyIf }}
-		<li>{{. | backticksToCodeTag | indentedToNewLines | escape }}</li>
-	  {{- end }}
-	  </ul>
+	  {{- template "validations" .OnlyIf }}
 	  {{- end }}
 	  <h4>Following conditions MUST be met:</h4>
+	  {{- template "validations" .Validations }}
+{{- end }}
+{{- define "validations" }}
 	  <ul>
-	  {{- range .Validations }}
-		<li>{{. | backticksToCodeTag | indentedToNewLines | escape }}</li>
+	  {{- range . }}
+		<li>{{ .Text | backticksToCodeTag | indentedToNewLines | escape }}{{ if .Children }}{{ template "validations" .Children }}{{ end }}</li>
 	  {{- end }}
 	  </ul>
 {{- end }}
@@ -34,15 +40,23 @@ var markdownTemplate = `
 {{- range .Rules }}
 
 ## {{.Name}}
+{{- if .IncludePaths }}
+Only for files matching: {{ .IncludePaths | escape }}
+{{- end }}
+{{- if .ExcludePaths }}
+Except files matching: {{ .ExcludePaths | escape }}
+{{- end }}
 {{- if .OnlyIf }}
 #### Only if ALL the following conditions are met:
-{{- range .OnlyIf }}
-  - {{. | escape}}
-{{- end }}
+{{- template "validations" .OnlyIf }}
 {{- end }}
 #### Following conditions MUST be met:
-{{- range .Validations }}
-  - {{. | escape}}
+{{- template "validations" .Validations }}
+{{- end }}
+{{- define "validations" }}
+{{- range . }}
+  {{ indent .Depth }}- {{ .Text | escape }}
+{{- template "validations" .Children }}
 {{- end }}
 {{- end }}
 `
@@ -52,16 +66,24 @@ Validation rules:
 {{- range .Rules }}
 
   {{.Name}} ({{.Scope}})
+	{{- if .IncludePaths }}
+    Only for files matching: {{ .IncludePaths | escape }}
+	{{- end }}
+	{{- if .ExcludePaths }}
+    Except files matching: {{ .ExcludePaths | escape }}
+	{{- end }}
 	{{- if .OnlyIf }}
     Only if ALL the following conditions are met:
-	{{- range .OnlyIf }}
-      - {{. | escape}}
-	{{- end }}
+	{{- template "validations" .OnlyIf }}
 	{{- end }}
     Following conditions MUST be met:
-    {{- range .Validations }}
-      - {{. | escape}}
-    {{- end }}
+    {{- template "validations" .Validations }}
+{{- end }}
+{{- define "validations" }}
+{{- range . }}
+      {{ indent .Depth }}- {{ .Text | escape }}
+{{- template "validations" .Children }}
+{{- end }}
 {{- end }}
 `
 
@@ -75,27 +97,48 @@ var customFuncs = template.FuncMap{
 	"escape": func(s string) template.HTML {
 		return template.HTML(s)
 	},
+	"indent": func(depth int) string {
+		return strings.Repeat("  ", depth)
+	},
+}
+
+// ValidationText describes a validation, validations composed of other validations have them described in the Children.
+type ValidationText struct {
+	Text     string
+	Depth    int
+	Children []ValidationText
 }
 
 type templateRule struct {
-	Name        string
-	Scope       string
-	Validations []string
-	OnlyIf      []string
+	Name         string
+	Scope        string
+	Validations  []ValidationText
+	OnlyIf       []ValidationText
+	IncludePaths string
+	ExcludePaths string
 }
 
 type templateData struct {
 	Rules []templateRule
 }
 
+func pathsText(paths []string) string {
+	if len(paths) == 0 {
+		return ""
+	}
+	return "`" + strings.Join(paths, "`, `") + "`"
+}
+
 func ValidationDocs(validationRules []ValidationRule, format string) (string, error) {
 	data := templateData{}
 	for _, rule := range validationRules {
 		data.Rules = append(data.Rules, templateRule{
-			Name:        rule.Name(),
-			Scope:       string(rule.Scope()),
-			Validations: rule.ValidationTexts(),
-			OnlyIf:      rule.OnlyIfValidationTexts(),
+			Name:         rule.Name(),
+			Scope:        string(rule.Scope()),
+			Validations:  rule.ValidationTexts(),
+			OnlyIf:       rule.OnlyIfValidationTexts(),
+			IncludePaths: pathsText(rule.IncludePaths()),
+			ExcludePaths: pathsText(rule.ExcludePaths()),
 		})
 	}
 
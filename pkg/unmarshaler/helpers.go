@@ -94,6 +94,23 @@ func unmarshalToNodeAndStruct(value, dstNode *yaml.Node, dstStruct interface{},
 	return value.Decode(dstStruct)
 }
 
+// checkUnsupportedFields fails if the mapping node contains any of the unsupported fields, the error is the same as for the unknown fields.
+func checkUnsupportedFields(node yaml.Node, dstStruct interface{}, knownFields, unsupportedFields []string) error {
+	if node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i < len(node.Content); i += 2 {
+		key := node.Content[i]
+		if slices.Contains(unsupportedFields, key.Value) {
+			supportedFields := slices.DeleteFunc(slices.Clone(knownFields), func(f string) bool {
+				return slices.Contains(unsupportedFields, f)
+			})
+			return fmt.Errorf("line %d: unknown field %q when unmarshaling into %T, supported fields are: %q", key.Line, key.Value, dstStruct, supportedFields)
+		}
+	}
+	return nil
+}
+
 // mustListStructYamlFieldNames returns a list of yaml field names for the given struct.
 // Fields that have the "omitempty" option in their yaml tag are never returned.
 func mustListStructYamlFieldNames(s interface{}, ignoreFields []string) []string {
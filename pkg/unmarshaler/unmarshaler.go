@@ -1,8 +1,10 @@
 package unmarshaler
 
 import (
+	"io"
 	"slices"
 	"strings"
+	"sync"
 
 	loki "github.com/grafana/loki/v3/pkg/tool/rules/rwrulefmt"
 	"github.com/prometheus/common/model"
@@ -12,22 +14,95 @@ import (
 	"github.com/fusakla/promruval/v3/pkg/config"
 )
 
+// Formats configures which extensions of the Prometheus rules format, used by other monitoring solutions, are supported.
+type Formats struct {
+	Loki   bool
+	Mimir  bool
+	Thanos bool
+}
+
+// globalFormats are supported in addition to the Formats passed to the DecodeRulesFile, kept only for the deprecated SupportLoki,
+// SupportMimir and SupportThanos.
 var (
-	supportLoki   = false
-	supportMimir  = false
-	supportThanos = false
+	globalFormats    Formats
+	globalFormatsMtx sync.Mutex
 )
 
+// SupportLoki sets whether the Loki format is supported by all the decoded rule files.
+//
+// Deprecated: Use the Formats passed to the DecodeRulesFile or the promruval.Options.
 func SupportLoki(support bool) {
-	supportLoki = support
+	globalFormatsMtx.Lock()
+	defer globalFormatsMtx.Unlock()
+	globalFormats.Loki = support
 }
 
+// SupportMimir sets whether the Mimir format is supported by all the decoded rule files.
+//
+// Deprecated: Use the Formats passed to the DecodeRulesFile or the promruval.Options.
 func SupportMimir(support bool) {
-	supportMimir = support
+	globalFormatsMtx.Lock()
+	defer globalFormatsMtx.Unlock()
+	globalFormats.Mimir = support
 }
 
+// SupportThanos sets whether the Thanos format is supported by all the decoded rule files.
+//
+// Deprecated: Use the Formats passed to the DecodeRulesFile or the promruval.Options.
 func SupportThanos(support bool) {
-	supportThanos = support
+	globalFormatsMtx.Lock()
+	defer globalFormatsMtx.Unlock()
+	globalFormats.Thanos = support
+}
+
+// withGlobalFormats returns the formats with the ones enabled by the deprecated SupportLoki, SupportMimir and SupportThanos added.
+func (f Formats) withGlobalFormats() Formats {
+	globalFormatsMtx.Lock()
+	defer globalFormatsMtx.Unlock()
+	return Formats{Loki: f.Loki || globalFormats.Loki, Mimir: f.Mimir || globalFormats.Mimir, Thanos: f.Thanos || globalFormats.Thanos}
+}
+
+func (f Formats) unsupportedRulesFileFields() []string {
+	var fields []string
+	if !f.Loki {
+		fields = append(fields, "namespace")
+	}
+	return fields
+}
+
+func (f Formats) unsupportedGroupFields() []string {
+	var fields []string
+	if !f.Loki {
+		fields = append(fields, "remote_write")
+	}
+	if !f.Thanos {
+		fields = append(fields, "partial_response_strategy")
+	}
+	if !f.Mimir {
+		fields = append(fields, "source_tenants")
+	}
+	return fields
+}
+
+// DecodeRulesFile decodes a single rules file document and fails on any field not supported by the given formats.
+// If the document is empty, the returned error wraps io.EOF.
+func DecodeRulesFile(reader io.Reader, formats Formats) (*RulesFileWithComment, error) {
+	var rf RulesFileWithComment
+	decoder := yaml.NewDecoder(reader)
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&rf); err != nil {
+		return nil, err
+	}
+	formats = formats.withGlobalFormats()
+	if err := checkUnsupportedFields(rf.node, &rf.RulesFile, rf.RulesFile.knownFields(), formats.unsupportedRulesFileFields()); err != nil { //nolint:staticcheck // must be called on the RuleFile so the yaml marshalling works
+		return nil, err
+	}
+	for _, group := range rf.Groups.Groups {
+		if err := checkUnsupportedFields(group.node, &group.RuleGroup, group.RuleGroup.knownFields(), formats.unsupportedGroupFields()); err != nil { //nolint:staticcheck // the knownFields must be called on the RuleGroup not the RuleGroupWithComment
+			return nil, err
+		}
+	}
+	return &rf, nil
 }
 
 type RulesFile struct {
@@ -42,11 +117,7 @@ type RulesFile struct {
 }
 
 func (r *RulesFile) knownFields() []string {
-	var ignoredFields []string
-	if !supportLoki {
-		ignoredFields = append(ignoredFields, "namespace")
-	}
-	return mustListStructYamlFieldNames(r, ignoredFields)
+	return mustListStructYamlFieldNames(r, nil)
 }
 
 type RulesFileWithComment struct {
@@ -91,6 +162,7 @@ type RuleGroup struct {
 	QueryOffset model.Duration    `yaml:"query_offset"`
 	Rules       []RuleWithComment `yaml:"rules"`
 	Limit       int               `yaml:"limit"`
+	Labels      map[string]string `yaml:"labels"`
 
 	// Thanos only
 	PartialResponseStrategy string `yaml:"partial_response_strategy"`
@@ -101,17 +173,7 @@ type RuleGroup struct {
 }
 
 func (r *RuleGroup) knownFields() []string {
-	var ignoredFields []string
-	if !supportLoki {
-		ignoredFields = append(ignoredFields, "remote_write")
-	}
-	if !supportThanos {
-		ignoredFields = append(ignoredFields, "partial_response_strategy")
-	}
-	if !supportMimir {
-		ignoredFields = append(ignoredFields, "source_tenants")
-	}
-	return mustListStructYamlFieldNames(r, ignoredFields)
+	return mustListStructYamlFieldNames(r, nil)
 }
 
 type RuleGroupWithComment struct {
@@ -133,6 +195,11 @@ type RuleWithComment struct {
 	rule rulefmt.Rule
 }
 
+// NewRuleWithComment wraps a rule which was not loaded from a YAML file, so it has no YAML comments.
+func NewRuleWithComment(rule rulefmt.Rule) RuleWithComment {
+	return RuleWithComment{rule: rule}
+}
+
 func (r *RuleWithComment) knownFields() []string {
 	// Struct fields marked as omitempty MUST be set to non-default value so they appear in marshalled yaml.
 	return mustListStructYamlFieldNames(rulefmt.Rule{Record: "foo", Alert: "bar", For: model.Duration(1), Labels: map[string]string{"foo": "bar"}, Annotations: map[string]string{"foo": "bar"}, KeepFiringFor: model.Duration(1)}, []string{})
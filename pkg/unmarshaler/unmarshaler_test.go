@@ -1,6 +1,7 @@
 package unmarshaler
 
 import (
+	"strings"
 	"testing"
 	"time"
 
@@ -10,17 +11,15 @@ import (
 	"github.com/prometheus/common/model"
 	"github.com/prometheus/prometheus/model/rulefmt"
 	"github.com/stretchr/testify/assert"
-	"gopkg.in/yaml.v3"
 )
 
 func TestUnmarshalling(t *testing.T) {
 	type testCase struct {
-		name          string
-		input         string
-		beforeExecute func()
-		afterExecute  func()
-		expected      RulesFileWithComment
-		error         bool
+		name     string
+		input    string
+		formats  Formats
+		expected RulesFileWithComment
+		error    bool
 	}
 
 	testCases := []testCase{
@@ -133,9 +132,8 @@ groups:
 			error: true,
 		},
 		{
-			name:          "thanos allowed fields",
-			beforeExecute: func() { SupportThanos(true) },
-			afterExecute:  func() { SupportThanos(false) },
+			name:    "thanos allowed fields",
+			formats: Formats{Thanos: true},
 			input: `
 groups:
   - name: group1
@@ -178,9 +176,8 @@ groups:
 			error: true,
 		},
 		{
-			name:          "loki allowed fields",
-			beforeExecute: func() { SupportLoki(true) },
-			afterExecute:  func() { SupportLoki(false) },
+			name:    "loki allowed fields",
+			formats: Formats{Loki: true},
 			input: `
 namespace: foo
 groups:
@@ -230,9 +227,8 @@ groups:
 			error: true,
 		},
 		{
-			name:          "mimir allowed fields",
-			beforeExecute: func() { SupportMimir(true) },
-			afterExecute:  func() { SupportMimir(false) },
+			name:    "mimir allowed fields",
+			formats: Formats{Mimir: true},
 			input: `
 groups:
   - name: group1
@@ -258,22 +254,31 @@ groups:
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			if tc.beforeExecute != nil {
-				tc.beforeExecute()
-			}
-			var actual RulesFileWithComment
-			err := yaml.Unmarshal([]byte(tc.input), &actual)
-			if tc.afterExecute != nil {
-				tc.afterExecute()
-			}
+			actual, err := DecodeRulesFile(strings.NewReader(tc.input), tc.formats)
 			if tc.error {
 				assert.Error(t, err)
 			} else {
 				assert.NoError(t, err)
-				if diff := cmp.Diff(tc.expected, actual, cmpopts.IgnoreUnexported(RulesFileWithComment{}, GroupsWithComment{}, RuleGroupWithComment{}, RuleWithComment{})); diff != "" {
+				if diff := cmp.Diff(tc.expected, *actual, cmpopts.IgnoreUnexported(RulesFileWithComment{}, GroupsWithComment{}, RuleGroupWithComment{}, RuleWithComment{})); diff != "" {
 					t.Errorf("Diff in unmarshalled struct: mismatch (-want +got):\n%s", diff)
 				}
 			}
 		})
 	}
 }
+
+func TestDeprecatedSupportFormats(t *testing.T) {
+	input := `
+groups:
+  - name: group1
+    source_tenants: [tenant1]
+    rules: []
+`
+	_, err := DecodeRulesFile(strings.NewReader(input), Formats{})
+	assert.ErrorContains(t, err, `unknown field "source_tenants"`)
+
+	SupportMimir(true)
+	t.Cleanup(func() { SupportMimir(false) })
+	_, err = DecodeRulesFile(strings.NewReader(input), Formats{})
+	assert.NoError(t, err)
+}
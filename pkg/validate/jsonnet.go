@@ -0,0 +1,57 @@
+package validate
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/google/go-jsonnet"
+	log "github.com/sirupsen/logrus"
+)
+
+// mixinSnippet evaluates the monitoring mixin imported from the file and returns the groups of its hidden prometheusAlerts and prometheusRules fields.
+// If the file is not a mixin, it evaluates to null.
+const mixinSnippet = `
+local mixin = import %s;
+local groups(field) = if std.objectHasAll(mixin, field) && std.objectHasAll(mixin[field], 'groups') then mixin[field].groups else [];
+if std.isObject(mixin) && (std.objectHasAll(mixin, 'prometheusAlerts') || std.objectHasAll(mixin, 'prometheusRules'))
+then { groups: groups('prometheusAlerts') + groups('prometheusRules') }
+else null
+`
+
+func isJsonnetFile(fileName string) bool {
+	return strings.HasSuffix(fileName, ".jsonnet") || strings.HasSuffix(fileName, ".libsonnet")
+}
+
+// evaluateJsonnet evaluates the jsonnet rule file. If it does not render the rule groups directly, but is a monitoring mixin,
+// the groups of its prometheusAlerts and prometheusRules are returned instead.
+func evaluateJsonnet(jsonnetVM *jsonnet.VM, fileName string) (string, error) {
+	output, err := jsonnetVM.EvaluateFile(fileName)
+	if err != nil {
+		return "", err
+	}
+	var rendered map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(output), &rendered); err == nil {
+		if _, ok := rendered["groups"]; ok {
+			return output, nil
+		}
+	}
+	absPath, err := filepath.Abs(fileName)
+	if err != nil {
+		return "", err
+	}
+	importPath, err := json.Marshal(absPath)
+	if err != nil {
+		return "", err
+	}
+	mixinOutput, err := jsonnetVM.EvaluateAnonymousSnippet(fileName, fmt.Sprintf(mixinSnippet, importPath))
+	if err != nil {
+		return "", fmt.Errorf("evaluating monitoring mixin: %w", err)
+	}
+	if strings.TrimSpace(mixinOutput) == "null" {
+		return output, nil
+	}
+	log.Debugf("file %s is a monitoring mixin, validating its prometheusAlerts and prometheusRules", fileName)
+	return mixinOutput, nil
+}
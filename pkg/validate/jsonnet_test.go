@@ -0,0 +1,65 @@
+package validate
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/fusakla/promruval/v3/pkg/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testMixin = `
+(import 'alerts.libsonnet') +
+{
+  _config+:: { selector: 'job="node"' },
+  prometheusRules+:: {
+    groups+: [{ name: 'node.rules', rules: [{ record: 'instance:up:count', expr: 'count(up{%(selector)s})' % $._config }] }],
+  },
+}
+`
+
+const testMixinAlerts = `
+{
+  prometheusAlerts+:: {
+    groups+: [{ name: 'node', rules: [{ alert: 'NodeDown', expr: 'up{%(selector)s} == 0' % $._config }] }],
+  },
+}
+`
+
+func TestEvaluateJsonnet(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"mixin/mixin.libsonnet":  testMixin,
+		"mixin/alerts.libsonnet": testMixinAlerts,
+		"rules.jsonnet":          `{ groups: [{ name: 'plain', rules: [] }] }`,
+		"empty.jsonnet":          `{}`,
+	}
+	for name, content := range files {
+		require.NoError(t, os.MkdirAll(filepath.Dir(filepath.Join(dir, name)), 0o700))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600))
+	}
+	tests := []struct {
+		file     string
+		expected string
+	}{
+		{
+			file: "mixin/mixin.libsonnet",
+			expected: `{"groups": [
+				{"name": "node", "rules": [{"alert": "NodeDown", "expr": "up{job=\"node\"} == 0"}]},
+				{"name": "node.rules", "rules": [{"record": "instance:up:count", "expr": "count(up{job=\"node\"})"}]}
+			]}`,
+		},
+		{file: "rules.jsonnet", expected: `{"groups": [{"name": "plain", "rules": []}]}`},
+		{file: "empty.jsonnet", expected: `{}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			fileName := filepath.Join(dir, tt.file)
+			output, err := evaluateJsonnet(config.JsonnetConfig{}.NewVM(fileName), fileName)
+			require.NoError(t, err)
+			assert.JSONEq(t, tt.expected, output)
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package validate
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"io"
@@ -21,7 +22,6 @@ import (
 	"github.com/google/go-jsonnet"
 	"github.com/prometheus/prometheus/model/rulefmt"
 	log "github.com/sirupsen/logrus"
-	"gopkg.in/yaml.v3"
 )
 
 func validateWithDetails(v validationrule.ValidatorWithDetails, group unmarshaler.RuleGroup, rule rulefmt.Rule, prometheusClient *prometheus.Client) []*report.Error {
@@ -41,185 +41,248 @@ func validateWithDetails(v validationrule.ValidatorWithDetails, group unmarshale
 	return errs
 }
 
-func validateFile(fileName string, fileIndex, fileCount int, validationRules []*validationrule.ValidationRule, excludeAnnotationName, disableValidationsComment string, prometheusClient *prometheus.Client, jsonnetVM *jsonnet.VM, validationReport *report.ValidationReport, disableParallelization bool) (groupsCount, rulesCount int, err error) {
-	log.WithFields(log.Fields{
-		"file":     fileName,
-		"progress": fmt.Sprintf("%d/%d", fileIndex+1, fileCount),
-	}).Info("processing file")
-
-	fileReport := validationReport.NewFileReport(fileName)
+// readRulesFile loads the YAML or jsonnet rules file, returns nil if the file is empty.
+func readRulesFile(fileName string, jsonnetVM *jsonnet.VM, formats unmarshaler.Formats) (*unmarshaler.RulesFileWithComment, error) {
 	var yamlReader io.Reader
-	groupsCount = 0
-	rulesCount = 0
-
 	switch {
-	case strings.HasSuffix(fileName, ".jsonnet"):
+	case isJsonnetFile(fileName):
 		log.Debugf("evaluating jsonnet file %s", fileName)
-		jsonnetOutput, err := jsonnetVM.EvaluateFile(fileName)
+		jsonnetOutput, err := evaluateJsonnet(jsonnetVM, fileName)
 		if err != nil {
-			fileReport.Valid = false
-			fileReport.Errors = []*report.Error{report.NewErrorf("cannot evaluate jsonnet file %s: %w", fileName, err)}
-			return groupsCount, rulesCount, err
+			return nil, fmt.Errorf("cannot evaluate jsonnet file %s: %w", fileName, err)
 		}
 		yamlReader = strings.NewReader(jsonnetOutput)
 	default:
-		var err error
-		yamlReader, err = os.Open(fileName)
+		f, err := os.Open(fileName)
 		if err != nil {
-			fileReport.Valid = false
-			fileReport.Errors = []*report.Error{report.NewErrorf("cannot read file %s: %w", fileName, err)}
-			return groupsCount, rulesCount, err
+			return nil, fmt.Errorf("cannot read file %s: %w", fileName, err)
 		}
+		defer f.Close()
+		yamlReader = f
 	}
-	var rf unmarshaler.RulesFileWithComment
-	decoder := yaml.NewDecoder(yamlReader)
-	decoder.KnownFields(true)
-	err = decoder.Decode(&rf)
+	rf, err := unmarshaler.DecodeRulesFile(yamlReader, formats)
 	if err != nil {
 		if errors.Is(err, io.EOF) {
-			return groupsCount, rulesCount, nil
+			return nil, nil
 		}
+		return nil, fmt.Errorf("invalid file %s: %w", fileName, err)
+	}
+	return rf, nil
+}
+
+func validateFile(fileName string, fileIndex, fileCount int, validationRules []*validationrule.ValidationRule, excludeAnnotationName, disableValidationsComment string, prometheusClients *prometheus.Backends, jsonnetVM *jsonnet.VM, formats unmarshaler.Formats, validationReport *report.ValidationReport, disableParallelization bool) (groupsCount, rulesCount int, err error) {
+	log.WithFields(log.Fields{
+		"file":     fileName,
+		"progress": fmt.Sprintf("%d/%d", fileIndex+1, fileCount),
+	}).Info("processing file")
+
+	fileReport := validationReport.NewFileReport(fileName)
+	groupsCount = 0
+	rulesCount = 0
+
+	rf, err := readRulesFile(fileName, jsonnetVM, formats)
+	if err != nil {
 		fileReport.Valid = false
-		fileReport.Errors = []*report.Error{report.NewErrorf("invalid file %s: %w", fileName, err)}
+		fileReport.Errors = []*report.Error{report.NewErrorf("%w", err)}
 		return groupsCount, rulesCount, err
 	}
+	if rf == nil {
+		return groupsCount, rulesCount, nil
+	}
+	validationRules = applicableValidationRules(validationRules, fileName)
 	fileDisabledValidators := rf.DisabledValidators(disableValidationsComment)
 	allGroupsDisabledValidators := rf.Groups.DisabledValidators(disableValidationsComment)
 	for _, group := range rf.Groups.Groups {
 		groupsCount++
-		groupReport := fileReport.NewGroupReport(group.Name)
-		groupDisabledValidators := group.DisabledValidators(disableValidationsComment)
-		if err := validator.KnownValidators(config.AllScope, groupDisabledValidators); err != nil {
-			groupReport.Errors = append(groupReport.Errors, report.NewErrorf("invalid disabled validators: %w", err))
+		rulesCount += validateGroup(fileName, group, slices.Concat(fileDisabledValidators, allGroupsDisabledValidators), validationRules, excludeAnnotationName, disableValidationsComment, prometheusClients, fileReport, disableParallelization)
+	}
+	return groupsCount, rulesCount, nil
+}
+
+// applicableValidationRules filters out validation rules limited to other paths than the validated file.
+func applicableValidationRules(validationRules []*validationrule.ValidationRule, fileName string) []*validationrule.ValidationRule {
+	applicable := make([]*validationrule.ValidationRule, 0, len(validationRules))
+	for _, rule := range validationRules {
+		if rule.AppliesToFile(fileName) {
+			applicable = append(applicable, rule)
+		} else {
+			log.Debugf("skipping validation rule %s for file %s because it does not match its paths", rule.Name(), fileName)
 		}
-		groupDisabledValidators = slices.Concat(groupDisabledValidators, fileDisabledValidators, allGroupsDisabledValidators)
+	}
+	return applicable
+}
 
-		var groupErrorsMutex sync.Mutex
-		var groupWg sync.WaitGroup
-	groupValidationLoop:
-		for _, rule := range validationRules {
-			if rule.Scope() != config.GroupScope {
+// validateGroup validates the group and all its rules, returns number of the validated rules.
+// The validation rules must be already filtered to the ones applicable to the file.
+func validateGroup(fileName string, group unmarshaler.RuleGroupWithComment, inheritedDisabledValidators []string, validationRules []*validationrule.ValidationRule, excludeAnnotationName, disableValidationsComment string, prometheusClients *prometheus.Backends, fileReport *report.FileReport, disableParallelization bool) (rulesCount int) {
+	groupReport := fileReport.NewGroupReport(group.Name)
+	groupDisabledValidators := group.DisabledValidators(disableValidationsComment)
+	if err := validator.KnownValidators(config.AllScope, groupDisabledValidators); err != nil {
+		groupReport.Errors = append(groupReport.Errors, report.NewErrorf("invalid disabled validators: %w", err))
+	}
+	groupDisabledValidators = slices.Concat(groupDisabledValidators, inheritedDisabledValidators)
+	groupPrometheusClient := prometheusClients.ClientFor(fileName, group.RuleGroup, nil)
+
+	var groupErrorsMutex sync.Mutex
+	var groupWg sync.WaitGroup
+groupValidationLoop:
+	for _, rule := range validationRules {
+		if rule.Scope() != config.GroupScope {
+			continue
+		}
+		for _, v := range rule.OnlyIf() {
+			if v.Scope() != config.GroupScope {
 				continue
 			}
-			for _, v := range rule.OnlyIf() {
-				if validator.Scope(v.Name()) != config.GroupScope {
-					continue
-				}
-				if errs := validateWithDetails(v, group.RuleGroup, rulefmt.Rule{}, prometheusClient); len(errs) > 0 {
-					log.Debugf("skipping validation of file %s group %s using \"%s\" because onlyIf results with errors: %v", fileName, group.Name, v, errs)
-					continue groupValidationLoop
-				}
+			if errs := validateWithDetails(v, group.RuleGroup, rulefmt.Rule{}, groupPrometheusClient); len(errs) > 0 {
+				log.Debugf("skipping validation of file %s group %s using \"%s\" because onlyIf results with errors: %v", fileName, group.Name, v, errs)
+				continue groupValidationLoop
 			}
-			for _, v := range rule.Validators() {
-				if slices.Contains(groupDisabledValidators, v.Name()) {
-					continue
-				}
-				groupWg.Add(1)
-				go func(validator validationrule.ValidatorWithDetails) {
-					defer groupWg.Done()
-					errs := validateWithDetails(validator, group.RuleGroup, rulefmt.Rule{}, prometheusClient)
-					if len(errs) > 0 {
-						groupErrorsMutex.Lock()
-						groupReport.Errors = append(groupReport.Errors, errs...)
-						groupErrorsMutex.Unlock()
-					}
-				}(v)
-				if disableParallelization {
-					groupWg.Wait()
+		}
+		for _, v := range rule.Validators() {
+			if slices.Contains(groupDisabledValidators, v.Name()) {
+				continue
+			}
+			groupWg.Add(1)
+			go func(validator validationrule.ValidatorWithDetails) {
+				defer groupWg.Done()
+				errs := validateWithDetails(validator, group.RuleGroup, rulefmt.Rule{}, groupPrometheusClient)
+				if len(errs) > 0 {
+					groupErrorsMutex.Lock()
+					groupReport.Errors = append(groupReport.Errors, errs...)
+					groupErrorsMutex.Unlock()
 				}
+			}(v)
+			if disableParallelization {
+				groupWg.Wait()
 			}
 		}
-		groupWg.Wait()
-		if len(groupReport.Errors) > 0 {
-			fileReport.Valid = false
-			groupReport.Valid = false
+	}
+	groupWg.Wait()
+	if len(groupReport.Errors) > 0 {
+		fileReport.Valid = false
+		groupReport.Valid = false
+	}
+	for _, ruleNode := range group.Rules {
+		rulesCount++
+		originalRule := ruleNode.OriginalRule()
+		var ruleReport *report.RuleReport
+		switch ruleNode.Scope() {
+		case config.AlertScope:
+			ruleReport = groupReport.NewRuleReport(originalRule.Alert, config.AlertScope)
+		case config.RecordingRuleScope:
+			ruleReport = groupReport.NewRuleReport(originalRule.Record, config.RecordingRuleScope)
 		}
-		for _, ruleNode := range group.Rules {
-			rulesCount++
-			originalRule := ruleNode.OriginalRule()
-			var ruleReport *report.RuleReport
-			switch ruleNode.Scope() {
-			case config.AlertScope:
-				ruleReport = groupReport.NewRuleReport(originalRule.Alert, config.AlertScope)
-			case config.RecordingRuleScope:
-				ruleReport = groupReport.NewRuleReport(originalRule.Record, config.RecordingRuleScope)
-			}
-			var excludedRules []string
-			excludedRulesText, ok := originalRule.Annotations[excludeAnnotationName]
-			if ok {
-				excludedRules = generateExcludedRules(excludedRulesText)
+		var excludedRules []string
+		excludedRulesText, ok := originalRule.Annotations[excludeAnnotationName]
+		if ok {
+			excludedRules = generateExcludedRules(excludedRulesText)
+		}
+		disabledValidators := ruleNode.DisabledValidators(disableValidationsComment)
+		if err := validator.KnownValidators(config.AllScope, disabledValidators); err != nil {
+			ruleReport.Errors = append(ruleReport.Errors, report.NewErrorf("invalid disabled validators: %w", err))
+		}
+		disabledValidators = append(disabledValidators, groupDisabledValidators...)
+		rulePrometheusClient := prometheusClients.ClientFor(fileName, group.RuleGroup, &originalRule)
+
+		var ruleErrorsMutex sync.Mutex
+		var ruleWg sync.WaitGroup
+	ruleValidationLoop:
+		for _, rule := range validationRules {
+			if rule.Scope() == config.GroupScope {
+				continue
 			}
-			disabledValidators := ruleNode.DisabledValidators(disableValidationsComment)
-			if err := validator.KnownValidators(config.AllScope, disabledValidators); err != nil {
-				ruleReport.Errors = append(ruleReport.Errors, report.NewErrorf("invalid disabled validators: %w", err))
+			if (rule.Scope() != ruleReport.RuleType) && (rule.Scope() != config.AllRulesScope) {
+				continue
 			}
-			disabledValidators = append(disabledValidators, groupDisabledValidators...)
-
-			var ruleErrorsMutex sync.Mutex
-			var ruleWg sync.WaitGroup
-		ruleValidationLoop:
-			for _, rule := range validationRules {
-				if rule.Scope() == config.GroupScope {
-					continue
-				}
-				if (rule.Scope() != ruleReport.RuleType) && (rule.Scope() != config.AllRulesScope) {
-					continue
+			for _, excludedRuleName := range excludedRules {
+				if excludedRuleName == rule.Name() {
+					continue ruleValidationLoop
 				}
-				for _, excludedRuleName := range excludedRules {
-					if excludedRuleName == rule.Name() {
+			}
+			for _, v := range rule.OnlyIf() {
+				if validator.MatchesScope(originalRule, ruleNode.Scope()) {
+					if errs := validateWithDetails(v, group.RuleGroup, originalRule, rulePrometheusClient); len(errs) > 0 {
+						log.Debugf("skipping validation of file %s group %s using \"%s\" because onlyIf results with errors: %v", fileName, group.Name, v, errs)
 						continue ruleValidationLoop
 					}
+				} else {
+					log.Debugf("skipping onlyIf validation of file %s group %s because it is not applicable: validator scrope: `%s`, rule scope: `%s`", fileName, group.Name, v.Scope(), ruleNode.Scope())
 				}
-				for _, v := range rule.OnlyIf() {
-					if validator.MatchesScope(originalRule, ruleNode.Scope()) {
-						if errs := validateWithDetails(v, group.RuleGroup, originalRule, prometheusClient); len(errs) > 0 {
-							log.Debugf("skipping validation of file %s group %s using \"%s\" because onlyIf results with errors: %v", fileName, group.Name, v, errs)
-							continue ruleValidationLoop
-						}
-					} else {
-						log.Debugf("skipping onlyIf validation of file %s group %s because it is not applicable: validator scrope: `%s`, rule scope: `%s`", fileName, group.Name, validator.Scope(v.Name()), ruleNode.Scope())
-					}
+			}
+			for _, v := range rule.Validators() {
+				validatorName := v.Name()
+				if slices.Contains(disabledValidators, validatorName) {
+					continue
 				}
-				for _, v := range rule.Validators() {
-					validatorName := v.Name()
-					if slices.Contains(disabledValidators, validatorName) {
-						continue
-					}
-					ruleWg.Add(1)
-					go func(validator validationrule.ValidatorWithDetails, grp unmarshaler.RuleGroup, rule rulefmt.Rule, vName string) {
-						defer ruleWg.Done()
-						validationStart := time.Now()
-						errs := validateWithDetails(validator, grp, rule, prometheusClient)
-						if len(errs) > 0 {
-							ruleErrorsMutex.Lock()
-							ruleReport.Errors = append(ruleReport.Errors, errs...)
-							ruleErrorsMutex.Unlock()
-						}
-						log.Debugf("validation of file %s group %s using \"%s\" took %s", fileName, group.Name, vName, time.Since(validationStart))
-					}(v, group.RuleGroup, originalRule, validatorName)
-					if disableParallelization {
-						ruleWg.Wait()
+				ruleWg.Add(1)
+				go func(validator validationrule.ValidatorWithDetails, grp unmarshaler.RuleGroup, rule rulefmt.Rule, vName string) {
+					defer ruleWg.Done()
+					validationStart := time.Now()
+					errs := validateWithDetails(validator, grp, rule, rulePrometheusClient)
+					if len(errs) > 0 {
+						ruleErrorsMutex.Lock()
+						ruleReport.Errors = append(ruleReport.Errors, errs...)
+						ruleErrorsMutex.Unlock()
 					}
+					log.Debugf("validation of file %s group %s using \"%s\" took %s", fileName, group.Name, vName, time.Since(validationStart))
+				}(v, group.RuleGroup, originalRule, validatorName)
+				if disableParallelization {
+					ruleWg.Wait()
 				}
 			}
-			ruleWg.Wait()
-			if len(ruleReport.Errors) > 0 {
-				fileReport.Valid = false
-				groupReport.Valid = false
-				ruleReport.Valid = false
-			}
+		}
+		ruleWg.Wait()
+		if len(ruleReport.Errors) > 0 {
+			fileReport.Valid = false
+			groupReport.Valid = false
+			ruleReport.Valid = false
 		}
 	}
-	return groupsCount, rulesCount, nil
+	return rulesCount
 }
 
-func Files(fileNames []string, validationRules []*validationrule.ValidationRule, excludeAnnotationName, disableValidationsComment string, prometheusClient *prometheus.Client, disableParallelization bool) *report.ValidationReport {
+func newValidationReport(validationRules []*validationrule.ValidationRule) *report.ValidationReport {
 	validationReport := report.NewValidationReport()
 	for _, r := range validationRules {
 		validationReport.ValidationRules = append(validationReport.ValidationRules, r)
 	}
+	return validationReport
+}
+
+// finishValidationReport sets the overall result and statistics of the report, statsBefore are the Prometheus clients stats at the start of the validation.
+func finishValidationReport(validationReport *report.ValidationReport, start time.Time, prometheusClients *prometheus.Backends, statsBefore prometheus.Stats) {
+	for _, fileReport := range validationReport.FilesReports {
+		if !fileReport.Valid {
+			validationReport.Failed = true
+		}
+	}
+	validationReport.Duration = time.Since(start)
+	if stats := prometheusClients.Stats().Sub(statsBefore); stats.Requests > 0 || stats.CacheHits > 0 {
+		validationReport.PrometheusStats = &report.PrometheusStats{
+			Requests:    stats.Requests,
+			Retries:     stats.Retries,
+			Throttled:   stats.Throttled,
+			Failed:      stats.Failed,
+			CacheHits:   stats.CacheHits,
+			CacheMisses: stats.CacheMisses,
+		}
+	}
+}
+
+// Files validates the given rule files using the single Prometheus client.
+//
+// Deprecated: Use the ValidateFiles.
+func Files(fileNames []string, validationRules []*validationrule.ValidationRule, excludeAnnotationName, disableValidationsComment string, prometheusClient *prometheus.Client, disableParallelization bool) *report.ValidationReport {
+	return ValidateFiles(context.Background(), fileNames, validationRules, excludeAnnotationName, disableValidationsComment, &prometheus.Backends{Default: prometheusClient}, unmarshaler.Formats{}, config.JsonnetConfig{}, disableParallelization)
+}
+
+// ValidateFiles validates the given rule files, files not yet started when the context is canceled are skipped.
+func ValidateFiles(ctx context.Context, fileNames []string, validationRules []*validationrule.ValidationRule, excludeAnnotationName, disableValidationsComment string, prometheusClients *prometheus.Backends, formats unmarshaler.Formats, jsonnetConfig config.JsonnetConfig, disableParallelization bool) *report.ValidationReport {
+	validationReport := newValidationReport(validationRules)
 
 	start := time.Now()
+	statsBefore := prometheusClients.Stats()
 	fileCount := len(fileNames)
 
 	var reportMutex sync.Mutex
@@ -227,11 +290,14 @@ func Files(fileNames []string, validationRules []*validationrule.ValidationRule,
 
 	// Create a jsonnet VM for each goroutine to avoid race conditions
 	for i, fileName := range fileNames {
+		if ctx.Err() != nil {
+			break
+		}
 		filesWg.Add(1)
 		go func(fileName string, fileIndex int) {
 			defer filesWg.Done()
-			jsonnetVM := jsonnet.MakeVM()
-			groupsCount, rulesCount, err := validateFile(fileName, fileIndex, fileCount, validationRules, excludeAnnotationName, disableValidationsComment, prometheusClient, jsonnetVM, validationReport, disableParallelization)
+			jsonnetVM := jsonnetConfig.NewVM(fileName)
+			groupsCount, rulesCount, err := validateFile(fileName, fileIndex, fileCount, validationRules, excludeAnnotationName, disableValidationsComment, prometheusClients, jsonnetVM, formats, validationReport, disableParallelization)
 			if err != nil {
 				log.WithError(err).Errorf("error validating file %s", fileName)
 			}
@@ -240,9 +306,6 @@ func Files(fileNames []string, validationRules []*validationrule.ValidationRule,
 			validationReport.FilesCount++
 			validationReport.GroupsCount += groupsCount
 			validationReport.RulesCount += rulesCount
-			if len(validationReport.FilesReports) > 0 && !validationReport.FilesReports[len(validationReport.FilesReports)-1].Valid {
-				validationReport.Failed = true
-			}
 			reportMutex.Unlock()
 		}(fileName, i)
 		if disableParallelization {
@@ -251,7 +314,27 @@ func Files(fileNames []string, validationRules []*validationrule.ValidationRule,
 	}
 
 	filesWg.Wait()
-	validationReport.Duration = time.Since(start)
+	finishValidationReport(validationReport, start, prometheusClients, statsBefore)
+	return validationReport
+}
+
+// Groups validates rule groups which were not loaded from a file, those are reported as a single file with the given sourceName.
+// Groups not yet started when the context is canceled are skipped.
+func Groups(ctx context.Context, sourceName string, groups []unmarshaler.RuleGroup, validationRules []*validationrule.ValidationRule, excludeAnnotationName, disableValidationsComment string, prometheusClients *prometheus.Backends, disableParallelization bool) *report.ValidationReport {
+	validationReport := newValidationReport(validationRules)
+	start := time.Now()
+	statsBefore := prometheusClients.Stats()
+	fileReport := validationReport.NewFileReport(sourceName)
+	validationReport.FilesCount = 1
+	validationRules = applicableValidationRules(validationRules, sourceName)
+	for _, group := range groups {
+		if ctx.Err() != nil {
+			break
+		}
+		validationReport.GroupsCount++
+		validationReport.RulesCount += validateGroup(sourceName, unmarshaler.RuleGroupWithComment{RuleGroup: group}, nil, validationRules, excludeAnnotationName, disableValidationsComment, prometheusClients, fileReport, disableParallelization)
+	}
+	finishValidationReport(validationReport, start, prometheusClients, statsBefore)
 	return validationReport
 }
 
@@ -267,15 +350,24 @@ func generateExcludedRules(excludedRulesText string) []string {
 	return slices.Compact(excludedRules)
 }
 
-func Cmd(filePaths []string, mainConfig *config.Config, validationRules []*validationrule.ValidationRule, supportLoki, supportMimir, supportThanos, disableParallelization bool) (*report.ValidationReport, error) {
+func expandHomeDir(path string) (string, error) {
+	if !strings.HasPrefix(path, "~/") {
+		return path, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get user home directory: %w", err)
+	}
+	return filepath.Join(home, path[2:]), nil
+}
+
+// ExpandFilePaths expands `~/` and double star globs in the file paths, fails if any of the patterns does not match any file.
+func ExpandFilePaths(filePaths []string) ([]string, error) {
 	var filesToBeValidated []string
 	for _, path := range filePaths {
-		if strings.HasPrefix(path, "~/") {
-			home, err := os.UserHomeDir()
-			if err != nil {
-				return nil, fmt.Errorf("failed to get user home directory: %w", err)
-			}
-			path = filepath.Join(home, path[2:])
+		path, err := expandHomeDir(path)
+		if err != nil {
+			return nil, err
 		}
 
 		base, pattern := doublestar.SplitPattern(path)
@@ -287,40 +379,63 @@ func Cmd(filePaths []string, mainConfig *config.Config, validationRules []*valid
 			filesToBeValidated = append(filesToBeValidated, filepath.Join(base, p))
 		}
 	}
+	return filesToBeValidated, nil
+}
 
-	if supportLoki {
-		unmarshaler.SupportLoki(true)
-	}
-
-	if supportMimir {
-		unmarshaler.SupportMimir(true)
-	}
-
-	if supportThanos {
-		unmarshaler.SupportThanos(true)
-	}
-
-	var err error
-	var prometheusClient *prometheus.Client
+// newPrometheusClients creates the client of the default Prometheus, if configured, and of the named backends.
+func newPrometheusClients(mainConfig *config.Config) (*prometheus.Backends, error) {
+	var defaultClient *prometheus.Client
 	if mainConfig.Prometheus.URL != "" {
-		prometheusClient, err = prometheus.NewClient(mainConfig.Prometheus)
+		var err error
+		defaultClient, err = prometheus.NewClient(mainConfig.Prometheus)
 		if err != nil {
 			return nil, fmt.Errorf("failed to initialize prometheus client: %w", err)
 		}
 	}
+	return prometheus.NewBackends(defaultClient, mainConfig.PrometheusBackends)
+}
+
+func dumpPrometheusCaches(prometheusClients *prometheus.Backends) {
+	if prometheusClients.Default != nil {
+		prometheusClients.Default.DumpCache()
+	}
+	prometheusClients.DumpCache()
+}
 
-	excludeAnnotation := "disabled_validation_rules"
+// ExcludeAnnotationAndDisableComment returns the annotation and comment prefix used for disabling validations, possibly customized in the config.
+func ExcludeAnnotationAndDisableComment(mainConfig *config.Config) (excludeAnnotation, disableValidatorsComment string) {
+	excludeAnnotation = "disabled_validation_rules"
 	if mainConfig.CustomExcludeAnnotation != "" {
 		excludeAnnotation = mainConfig.CustomExcludeAnnotation
 	}
-	disableValidatorsComment := "ignore_validations"
+	disableValidatorsComment = "ignore_validations"
 	if mainConfig.CustomDisableComment != "" {
 		disableValidatorsComment = mainConfig.CustomDisableComment
 	}
-	validationReport := Files(filesToBeValidated, validationRules, excludeAnnotation, disableValidatorsComment, prometheusClient, disableParallelization)
+	return excludeAnnotation, disableValidatorsComment
+}
 
-	if mainConfig.Prometheus.URL != "" {
-		prometheusClient.DumpCache()
+// Cmd validates the rule files matching the file paths.
+//
+// Deprecated: Use the CmdWithFormats.
+func Cmd(filePaths []string, mainConfig *config.Config, validationRules []*validationrule.ValidationRule, supportLoki, supportMimir, supportThanos, disableParallelization bool) (*report.ValidationReport, error) {
+	return CmdWithFormats(filePaths, mainConfig, validationRules, unmarshaler.Formats{Loki: supportLoki, Mimir: supportMimir, Thanos: supportThanos}, disableParallelization)
+}
+
+// CmdWithFormats validates the rule files matching the file paths, supporting the given extensions of the rules format.
+func CmdWithFormats(filePaths []string, mainConfig *config.Config, validationRules []*validationrule.ValidationRule, formats unmarshaler.Formats, disableParallelization bool) (*report.ValidationReport, error) {
+	filesToBeValidated, err := ExpandFilePaths(filePaths)
+	if err != nil {
+		return nil, err
 	}
+
+	prometheusClients, err := newPrometheusClients(mainConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	excludeAnnotation, disableValidatorsComment := ExcludeAnnotationAndDisableComment(mainConfig)
+	validationReport := ValidateFiles(context.Background(), filesToBeValidated, validationRules, excludeAnnotation, disableValidatorsComment, prometheusClients, formats, mainConfig.Jsonnet, disableParallelization)
+	dumpPrometheusCaches(prometheusClients)
 	return validationReport, nil
 }
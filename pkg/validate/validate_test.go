@@ -1,9 +1,14 @@
 package validate
 
 import (
+	"os"
+	"path/filepath"
 	"testing"
 
+	"github.com/fusakla/promruval/v3/pkg/config"
+	"github.com/fusakla/promruval/v3/pkg/extractvalidators"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestGenerateExcludedRules(t *testing.T) {
@@ -60,3 +65,24 @@ func TestGenerateExcludedRules(t *testing.T) {
 		})
 	}
 }
+
+func TestDeprecatedCmd(t *testing.T) {
+	dir := t.TempDir()
+	configFile := filepath.Join(dir, "validation.yaml")
+	require.NoError(t, os.WriteFile(configFile, []byte(watchTestConfig), 0o600))
+	rulesFile := filepath.Join(dir, "rules.yaml")
+	require.NoError(t, os.WriteFile(rulesFile, []byte(watchTestInvalidRules), 0o600))
+	mainConfig, err := config.LoadConfiguration([]string{configFile})
+	require.NoError(t, err)
+	validationRules, err := extractvalidators.ValidationRulesFromConfig(mainConfig, nil, nil)
+	require.NoError(t, err)
+
+	validationReport, err := Cmd([]string{rulesFile}, mainConfig, validationRules, false, true, false, true)
+	require.NoError(t, err)
+	assert.True(t, validationReport.Failed)
+	assert.Equal(t, 1, validationReport.RulesCount)
+
+	validationReport = Files([]string{rulesFile}, validationRules, "disabled_validation_rules", "ignore_validations", nil, true)
+	assert.True(t, validationReport.Failed)
+	assert.Equal(t, 1, validationReport.RulesCount)
+}
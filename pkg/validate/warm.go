@@ -0,0 +1,81 @@
+package validate
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+
+	"github.com/fusakla/promruval/v3/pkg/config"
+	"github.com/fusakla/promruval/v3/pkg/prometheus"
+	"github.com/fusakla/promruval/v3/pkg/unmarshaler"
+	"github.com/fusakla/promruval/v3/pkg/validationrule"
+	"github.com/fusakla/promruval/v3/pkg/validator"
+	"github.com/google/go-jsonnet"
+	"github.com/prometheus/prometheus/model/rulefmt"
+	log "github.com/sirupsen/logrus"
+)
+
+// WarmCache sends the Prometheus queries needed by the validation rules for the rule files and dumps the caches, the rules are not validated.
+// The onlyIf conditions, disabled validators and excluded rules are not taken into account, so all the possibly needed data are cached.
+// Returns the stats of the Prometheus clients.
+func WarmCache(ctx context.Context, filePaths []string, mainConfig *config.Config, validationRules []*validationrule.ValidationRule, formats unmarshaler.Formats) (prometheus.Stats, error) {
+	filesToWarm, err := ExpandFilePaths(filePaths)
+	if err != nil {
+		return prometheus.Stats{}, err
+	}
+	prometheusClients, err := newPrometheusClients(mainConfig)
+	if err != nil {
+		return prometheus.Stats{}, err
+	}
+	var wg sync.WaitGroup
+	for _, fileName := range filesToWarm {
+		if ctx.Err() != nil {
+			break
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			log.WithField("file", fileName).Info("warming cache for file")
+			if err := warmFile(fileName, validationRules, prometheusClients, mainConfig.Jsonnet.NewVM(fileName), formats); err != nil {
+				log.WithError(err).Warnf("failed to warm cache for file %s", fileName)
+			}
+		}()
+	}
+	wg.Wait()
+	dumpPrometheusCaches(prometheusClients)
+	return prometheusClients.Stats(), nil
+}
+
+func warmFile(fileName string, validationRules []*validationrule.ValidationRule, prometheusClients *prometheus.Backends, jsonnetVM *jsonnet.VM, formats unmarshaler.Formats) error {
+	rf, err := readRulesFile(fileName, jsonnetVM, formats)
+	if err != nil || rf == nil {
+		return err
+	}
+	validationRules = applicableValidationRules(validationRules, fileName)
+	var errs []error
+	for _, group := range rf.Groups.Groups {
+		groupPrometheusClient := prometheusClients.ClientFor(fileName, group.RuleGroup, nil)
+		for _, rule := range validationRules {
+			if rule.Scope() != config.GroupScope {
+				continue
+			}
+			for _, v := range slices.Concat(rule.OnlyIf(), rule.Validators()) {
+				errs = append(errs, validator.WarmCache(v, group.RuleGroup, rulefmt.Rule{}, groupPrometheusClient))
+			}
+		}
+		for _, ruleNode := range group.Rules {
+			originalRule := ruleNode.OriginalRule()
+			rulePrometheusClient := prometheusClients.ClientFor(fileName, group.RuleGroup, &originalRule)
+			for _, rule := range validationRules {
+				if rule.Scope() != ruleNode.Scope() && rule.Scope() != config.AllRulesScope {
+					continue
+				}
+				for _, v := range slices.Concat(rule.OnlyIf(), rule.Validators()) {
+					errs = append(errs, validator.WarmCache(v, group.RuleGroup, originalRule, rulePrometheusClient))
+				}
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
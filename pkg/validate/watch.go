@@ -0,0 +1,273 @@
+package validate
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/bmatcuk/doublestar/v4"
+	"github.com/fsnotify/fsnotify"
+	"github.com/fusakla/promruval/v3/pkg/config"
+	"github.com/fusakla/promruval/v3/pkg/prometheus"
+	"github.com/fusakla/promruval/v3/pkg/report"
+	"github.com/fusakla/promruval/v3/pkg/unmarshaler"
+	"github.com/fusakla/promruval/v3/pkg/validationrule"
+	log "github.com/sirupsen/logrus"
+)
+
+// Editors usually write files in several steps, wait for the events to settle down before re-running the validation.
+const watchDebounce = 200 * time.Millisecond
+
+// ConfigLoader loads the validation config and the validation rules, it is called again whenever any of the watched config files changes.
+type ConfigLoader func() (*config.Config, []*validationrule.ValidationRule, error)
+
+type watchState struct {
+	filePaths              []string
+	configFiles            []string
+	loadConfig             ConfigLoader
+	formats                unmarshaler.Formats
+	disableParallelization bool
+	onReport               func(*report.ValidationReport)
+
+	watcher           *fsnotify.Watcher
+	mainConfig        *config.Config
+	validationRules   []*validationrule.ValidationRule
+	prometheusClients *prometheus.Backends
+}
+
+// Watch validates all the files matching filePaths and then keeps running until the context is canceled.
+// Whenever a rule file changes, only the changed files are validated again using the already loaded validators and Prometheus client.
+// If any of the config files changes, the configuration is reloaded and all the files are validated again.
+func Watch(ctx context.Context, filePaths, configFiles []string, loadConfig ConfigLoader, formats unmarshaler.Formats, disableParallelization bool, onReport func(*report.ValidationReport)) error {
+	mainConfig, validationRules, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	s := &watchState{
+		filePaths:              filePaths,
+		loadConfig:             loadConfig,
+		formats:                formats,
+		disableParallelization: disableParallelization,
+		onReport:               onReport,
+		mainConfig:             mainConfig,
+		validationRules:        validationRules,
+	}
+	// The validation rules are replaced on each config reload, close the last ones.
+	defer func() {
+		_ = validationrule.CloseAll(s.validationRules)
+	}()
+	s.prometheusClients, err = newPrometheusClients(mainConfig)
+	if err != nil {
+		return err
+	}
+	s.watcher, err = fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("failed to initialize file watcher: %w", err)
+	}
+	defer s.watcher.Close()
+
+	absConfigFiles := make([]string, 0, len(configFiles))
+	for _, f := range configFiles {
+		absPath, err := filepath.Abs(f)
+		if err != nil {
+			return fmt.Errorf("failed to get absolute path of config file %s: %w", f, err)
+		}
+		absConfigFiles = append(absConfigFiles, absPath)
+	}
+	s.configFiles = absConfigFiles
+	s.watchConfigFiles(mainConfig)
+	if err := s.watchFilePathsDirs(); err != nil {
+		return err
+	}
+	files, err := s.expandAndWatch()
+	if err != nil {
+		return err
+	}
+	s.validate(ctx, files)
+
+	changedFiles := map[string]struct{}{}
+	var debounce <-chan time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case err, ok := <-s.watcher.Errors:
+			if !ok {
+				return nil
+			}
+			log.WithError(err).Warn("file watcher error")
+		case event, ok := <-s.watcher.Events:
+			if !ok {
+				return nil
+			}
+			if !event.Has(fsnotify.Write) && !event.Has(fsnotify.Create) {
+				continue
+			}
+			if event.Has(fsnotify.Create) {
+				if info, err := os.Stat(event.Name); err == nil && info.IsDir() {
+					// Files might have been created in the new directory before it was watched.
+					for _, f := range s.watchTree(event.Name) {
+						if absPath, err := filepath.Abs(f); err == nil {
+							changedFiles[absPath] = struct{}{}
+						}
+					}
+					debounce = time.After(watchDebounce)
+					continue
+				}
+			}
+			absPath, err := filepath.Abs(event.Name)
+			if err != nil {
+				continue
+			}
+			changedFiles[absPath] = struct{}{}
+			debounce = time.After(watchDebounce)
+		case <-debounce:
+			debounce = nil
+			s.handleChanges(ctx, changedFiles)
+			clear(changedFiles)
+		}
+	}
+}
+
+func (s *watchState) handleChanges(ctx context.Context, changedFiles map[string]struct{}) {
+	configChanged := false
+	for _, f := range s.configFiles {
+		if _, ok := changedFiles[f]; ok {
+			configChanged = true
+			break
+		}
+	}
+	if configChanged {
+		log.Info("config file changed, reloading the configuration")
+		if err := s.reloadConfig(); err != nil {
+			log.WithError(err).Error("failed to reload the configuration, keeping the previous one")
+			return
+		}
+	}
+	files, err := s.expandAndWatch()
+	if err != nil {
+		log.WithError(err).Error("failed to expand the rule file paths")
+		return
+	}
+	if !configChanged {
+		files = slices.DeleteFunc(files, func(f string) bool {
+			absPath, err := filepath.Abs(f)
+			if err != nil {
+				return true
+			}
+			_, ok := changedFiles[absPath]
+			return !ok
+		})
+	}
+	if len(files) == 0 {
+		return
+	}
+	s.validate(ctx, files)
+}
+
+// watchConfigFiles watches also the config files included from the ones passed using flags.
+func (s *watchState) watchConfigFiles(mainConfig *config.Config) {
+	for _, f := range mainConfig.Files {
+		if !slices.Contains(s.configFiles, f) {
+			s.configFiles = append(s.configFiles, f)
+		}
+	}
+	for _, f := range s.configFiles {
+		s.watchDir(filepath.Dir(f))
+	}
+}
+
+func (s *watchState) reloadConfig() error {
+	mainConfig, validationRules, err := s.loadConfig()
+	if err != nil {
+		return err
+	}
+	s.watchConfigFiles(mainConfig)
+	if !reflect.DeepEqual(mainConfig.Prometheus, s.mainConfig.Prometheus) || !reflect.DeepEqual(mainConfig.PrometheusBackends, s.mainConfig.PrometheusBackends) {
+		prometheusClients, err := newPrometheusClients(mainConfig)
+		if err != nil {
+			_ = validationrule.CloseAll(validationRules)
+			return err
+		}
+		dumpPrometheusCaches(s.prometheusClients)
+		s.prometheusClients = prometheusClients
+	}
+	if err := validationrule.CloseAll(s.validationRules); err != nil {
+		log.WithError(err).Warn("failed to close the previous validation rules")
+	}
+	s.mainConfig = mainConfig
+	s.validationRules = validationRules
+	return nil
+}
+
+func (s *watchState) validate(ctx context.Context, files []string) {
+	excludeAnnotation, disableValidatorsComment := ExcludeAnnotationAndDisableComment(s.mainConfig)
+	s.onReport(ValidateFiles(ctx, files, s.validationRules, excludeAnnotation, disableValidatorsComment, s.prometheusClients, s.formats, s.mainConfig.Jsonnet, s.disableParallelization))
+	dumpPrometheusCaches(s.prometheusClients)
+}
+
+// watchFilePathsDirs watches the base directories of the file path globs, since fsnotify is not recursive,
+// all the subdirectories are watched too if the glob can match files in them.
+func (s *watchState) watchFilePathsDirs() error {
+	for _, p := range s.filePaths {
+		p, err := expandHomeDir(p)
+		if err != nil {
+			return err
+		}
+		base, pattern := doublestar.SplitPattern(p)
+		if strings.Contains(pattern, "/") {
+			s.watchTree(base)
+		} else {
+			s.watchDir(base)
+		}
+	}
+	return nil
+}
+
+// watchTree watches the directory and all its subdirectories, returns the files found in them.
+func (s *watchState) watchTree(root string) []string {
+	var files []string
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			log.WithError(err).WithField("path", path).Warn("failed to walk directory to watch it for changes")
+			return nil
+		}
+		if d.IsDir() {
+			s.watchDir(path)
+		} else {
+			files = append(files, path)
+		}
+		return nil
+	})
+	if err != nil {
+		log.WithError(err).WithField("dir", root).Warn("failed to watch directory for changes")
+	}
+	return files
+}
+
+// expandAndWatch expands the file path globs and makes sure all directories that may contain matching files are watched.
+func (s *watchState) expandAndWatch() ([]string, error) {
+	files, err := ExpandFilePaths(s.filePaths)
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range files {
+		s.watchDir(filepath.Dir(f))
+	}
+	return files, nil
+}
+
+func (s *watchState) watchDir(dir string) {
+	if slices.Contains(s.watcher.WatchList(), dir) {
+		return
+	}
+	if err := s.watcher.Add(dir); err != nil {
+		log.WithError(err).WithField("dir", dir).Warn("failed to watch directory for changes")
+	}
+}
@@ -0,0 +1,152 @@
+package validate
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/fusakla/promruval/v3/pkg/config"
+	"github.com/fusakla/promruval/v3/pkg/extractvalidators"
+	"github.com/fusakla/promruval/v3/pkg/report"
+	"github.com/fusakla/promruval/v3/pkg/unmarshaler"
+	"github.com/fusakla/promruval/v3/pkg/validationrule"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const watchTestConfig = `
+validationRules:
+  - name: test
+    scope: All rules
+    validations:
+      - type: hasLabels
+        params:
+          labels: ["severity"]
+`
+
+const watchTestValidRules = `
+groups:
+  - name: test
+    rules:
+      - record: foo
+        expr: 1
+        labels:
+          severity: info
+`
+
+const watchTestInvalidRules = `
+groups:
+  - name: test
+    rules:
+      - record: foo
+        expr: 1
+`
+
+func waitForReport(t *testing.T, reports <-chan *report.ValidationReport) *report.ValidationReport {
+	t.Helper()
+	select {
+	case r := <-reports:
+		return r
+	case <-time.After(10 * time.Second):
+		t.Fatal("timed out waiting for validation report")
+		return nil
+	}
+}
+
+func TestWatch(t *testing.T) {
+	dir := t.TempDir()
+	rulesDir := filepath.Join(dir, "rules")
+	require.NoError(t, os.Mkdir(rulesDir, 0o700))
+	configFile := filepath.Join(dir, "validation.yaml")
+	validRulesFile := filepath.Join(rulesDir, "valid.yaml")
+	changedRulesFile := filepath.Join(rulesDir, "changed.yaml")
+	require.NoError(t, os.WriteFile(configFile, []byte(watchTestConfig), 0o600))
+	require.NoError(t, os.WriteFile(validRulesFile, []byte(watchTestValidRules), 0o600))
+	require.NoError(t, os.WriteFile(changedRulesFile, []byte(watchTestValidRules), 0o600))
+
+	loadConfig := func() (*config.Config, []*validationrule.ValidationRule, error) {
+		cfg, err := config.LoadConfiguration([]string{configFile})
+		if err != nil {
+			return nil, nil, err
+		}
+		rules, err := extractvalidators.ValidationRulesFromConfig(cfg, nil, nil)
+		return cfg, rules, err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	reports := make(chan *report.ValidationReport, 10)
+	done := make(chan error)
+	go func() {
+		done <- Watch(ctx, []string{filepath.Join(rulesDir, "*.yaml")}, []string{configFile}, loadConfig, unmarshaler.Formats{}, false, func(r *report.ValidationReport) {
+			reports <- r
+		})
+	}()
+
+	initial := waitForReport(t, reports)
+	assert.False(t, initial.Failed)
+	assert.Equal(t, 2, initial.FilesCount)
+
+	require.NoError(t, os.WriteFile(changedRulesFile, []byte(watchTestInvalidRules), 0o600))
+	changed := waitForReport(t, reports)
+	assert.True(t, changed.Failed)
+	assert.Equal(t, 1, changed.FilesCount)
+	assert.Equal(t, changedRulesFile, changed.FilesReports[0].Name)
+
+	cancel()
+	assert.NoError(t, <-done)
+}
+
+func TestWatchSubdirectories(t *testing.T) {
+	dir := t.TempDir()
+	rulesDir := filepath.Join(dir, "rules")
+	emptyDir := filepath.Join(rulesDir, "empty")
+	require.NoError(t, os.MkdirAll(emptyDir, 0o700))
+	configFile := filepath.Join(dir, "validation.yaml")
+	require.NoError(t, os.WriteFile(configFile, []byte(watchTestConfig), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(rulesDir, "valid.yaml"), []byte(watchTestValidRules), 0o600))
+
+	loadConfig := func() (*config.Config, []*validationrule.ValidationRule, error) {
+		cfg, err := config.LoadConfiguration([]string{configFile})
+		if err != nil {
+			return nil, nil, err
+		}
+		rules, err := extractvalidators.ValidationRulesFromConfig(cfg, nil, nil)
+		return cfg, rules, err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	reports := make(chan *report.ValidationReport, 10)
+	done := make(chan error)
+	go func() {
+		done <- Watch(ctx, []string{filepath.Join(rulesDir, "**", "*.yaml")}, []string{configFile}, loadConfig, unmarshaler.Formats{}, false, func(r *report.ValidationReport) {
+			reports <- r
+		})
+	}()
+
+	initial := waitForReport(t, reports)
+	assert.False(t, initial.Failed)
+	assert.Equal(t, 1, initial.FilesCount)
+
+	existingSubdirFile := filepath.Join(emptyDir, "rules.yaml")
+	require.NoError(t, os.WriteFile(existingSubdirFile, []byte(watchTestInvalidRules), 0o600))
+	changed := waitForReport(t, reports)
+	assert.True(t, changed.Failed)
+	assert.Equal(t, 1, changed.FilesCount)
+	assert.Equal(t, existingSubdirFile, changed.FilesReports[0].Name)
+
+	newSubdir := filepath.Join(rulesDir, "new", "nested")
+	require.NoError(t, os.MkdirAll(newSubdir, 0o700))
+	newSubdirFile := filepath.Join(newSubdir, "rules.yaml")
+	require.NoError(t, os.WriteFile(newSubdirFile, []byte(watchTestInvalidRules), 0o600))
+	created := waitForReport(t, reports)
+	assert.True(t, created.Failed)
+	assert.Equal(t, 1, created.FilesCount)
+	assert.Equal(t, newSubdirFile, created.FilesReports[0].Name)
+
+	cancel()
+	assert.NoError(t, <-done)
+}
@@ -2,17 +2,26 @@ package validationrule
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"path/filepath"
 	"reflect"
+	"slices"
 
+	"github.com/bmatcuk/doublestar/v4"
 	"github.com/fusakla/promruval/v3/pkg/config"
+	"github.com/fusakla/promruval/v3/pkg/prometheus"
+	"github.com/fusakla/promruval/v3/pkg/report"
+	"github.com/fusakla/promruval/v3/pkg/unmarshaler"
 	"github.com/fusakla/promruval/v3/pkg/validator"
+	"github.com/prometheus/prometheus/model/rulefmt"
 )
 
 type ValidatorWithDetails interface {
 	validator.Validator
 	AdditionalDetails() string
 	Name() string
+	Scope() config.ValidationScope
 }
 
 type validatorWithAdditionalDetails struct {
@@ -29,6 +38,21 @@ func (v validatorWithAdditionalDetails) Name() string {
 	return v.name
 }
 
+// WarmCache fills the Prometheus query cache with the data needed by the wrapped validator.
+func (v validatorWithAdditionalDetails) WarmCache(group unmarshaler.RuleGroup, rule rulefmt.Rule, prometheusClient *prometheus.Client) error {
+	return validator.WarmCache(v.Validator, group, rule, prometheusClient)
+}
+
+// Close releases the resources held by the wrapped validator.
+func (v validatorWithAdditionalDetails) Close() error {
+	return validator.Close(v.Validator)
+}
+
+// Scope returns the scope of the validator, in case of logical operators it is the common scope of all the operands.
+func (v validatorWithAdditionalDetails) Scope() config.ValidationScope {
+	return validator.Operand{Validator: v.Validator, Name: v.name}.Scope()
+}
+
 func New(name string, scope config.ValidationScope) *ValidationRule {
 	return &ValidationRule{
 		name:       name,
@@ -38,17 +62,23 @@ func New(name string, scope config.ValidationScope) *ValidationRule {
 }
 
 type ValidationRule struct {
-	name       string
-	scope      config.ValidationScope
-	onlyIf     []ValidatorWithDetails
-	validators []ValidatorWithDetails
+	name         string
+	scope        config.ValidationScope
+	onlyIf       []ValidatorWithDetails
+	validators   []ValidatorWithDetails
+	includePaths []string
+	excludePaths []string
+	source       string
 }
 
 type MarshalableValidationRule struct {
-	Name       string                 `json:"name" yaml:"name"`
-	Scope      config.ValidationScope `json:"scope" yaml:"scope"`
-	Validators []string               `json:"validators" yaml:"validators"`
-	OnlyIf     []string               `json:"only_if" yaml:"only_if"`
+	Name         string                 `json:"name" yaml:"name"`
+	Scope        config.ValidationScope `json:"scope" yaml:"scope"`
+	Validators   []string               `json:"validators" yaml:"validators"`
+	OnlyIf       []string               `json:"only_if" yaml:"only_if"`
+	IncludePaths []string               `json:"include_paths,omitempty" yaml:"include_paths,omitempty"`
+	ExcludePaths []string               `json:"exclude_paths,omitempty" yaml:"exclude_paths,omitempty"`
+	Source       string                 `json:"source,omitempty" yaml:"source,omitempty"`
 }
 
 func (r *ValidationRule) Validators() []ValidatorWithDetails {
@@ -60,21 +90,90 @@ func (r *ValidationRule) OnlyIf() []ValidatorWithDetails {
 }
 
 func (r *ValidationRule) AddValidator(newValidator validator.Validator, additionalDetails string) {
+	r.AddNamedValidator(reflect.TypeOf(newValidator).Elem().Name(), newValidator, additionalDetails)
+}
+
+func (r *ValidationRule) AddOnlyIfValidator(newValidator validator.Validator, additionalDetails string) {
+	r.AddNamedOnlyIfValidator(reflect.TypeOf(newValidator).Elem().Name(), newValidator, additionalDetails)
+}
+
+// AddNamedValidator adds a validator, the name must be the validator type it was registered with.
+func (r *ValidationRule) AddNamedValidator(name string, newValidator validator.Validator, additionalDetails string) {
 	r.validators = append(r.validators, &validatorWithAdditionalDetails{
 		Validator:         newValidator,
 		additionalDetails: additionalDetails,
-		name:              reflect.TypeOf(newValidator).Elem().Name(),
+		name:              name,
 	})
 }
 
-func (r *ValidationRule) AddOnlyIfValidator(newValidator validator.Validator, additionalDetails string) {
+// AddNamedOnlyIfValidator adds a validator used as a condition, the name must be the validator type it was registered with.
+func (r *ValidationRule) AddNamedOnlyIfValidator(name string, newValidator validator.Validator, additionalDetails string) {
 	r.onlyIf = append(r.onlyIf, &validatorWithAdditionalDetails{
 		Validator:         newValidator,
 		additionalDetails: additionalDetails,
-		name:              reflect.TypeOf(newValidator).Elem().Name(),
+		name:              name,
 	})
 }
 
+// SetPaths limits the rule to the files matching any of the include double star globs (all files if empty) and none of the exclude ones.
+func (r *ValidationRule) SetPaths(includePaths, excludePaths []string) {
+	r.includePaths = includePaths
+	r.excludePaths = excludePaths
+}
+
+// SetSource sets the path to the config file the rule was loaded from.
+func (r *ValidationRule) SetSource(source string) {
+	r.source = source
+}
+
+// Close releases the resources held by the validators of the rule, such as the WASM plugin runtimes.
+// The rule must not be used after it is closed.
+func (r *ValidationRule) Close() error {
+	var errs []error
+	for _, v := range slices.Concat(r.onlyIf, r.validators) {
+		errs = append(errs, validator.Close(v))
+	}
+	return errors.Join(errs...)
+}
+
+// CloseAll closes all the validation rules, see Close.
+func CloseAll(validationRules []*ValidationRule) error {
+	var errs []error
+	for _, r := range validationRules {
+		errs = append(errs, r.Close())
+	}
+	return errors.Join(errs...)
+}
+
+func (r *ValidationRule) Source() string {
+	return r.source
+}
+
+func (r *ValidationRule) IncludePaths() []string {
+	return r.includePaths
+}
+
+func (r *ValidationRule) ExcludePaths() []string {
+	return r.excludePaths
+}
+
+// AppliesToFile returns true if the rule should be used to validate the file with the given path.
+func (r *ValidationRule) AppliesToFile(filePath string) bool {
+	filePath = filepath.ToSlash(filepath.Clean(filePath))
+	matchesAny := func(patterns []string) bool {
+		for _, pattern := range patterns {
+			if doublestar.MatchUnvalidated(pattern, filePath) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(r.includePaths) > 0 && !matchesAny(r.includePaths) {
+		return false
+	}
+	return !matchesAny(r.excludePaths)
+}
+
 func (r *ValidationRule) Name() string {
 	return r.name
 }
@@ -85,10 +184,13 @@ func (r *ValidationRule) Scope() config.ValidationScope {
 
 func (r *ValidationRule) AsMarshalable() *MarshalableValidationRule {
 	out := &MarshalableValidationRule{
-		Name:       r.Name(),
-		Scope:      r.Scope(),
-		Validators: make([]string, 0, len(r.validators)),
-		OnlyIf:     make([]string, 0, len(r.onlyIf)),
+		Name:         r.Name(),
+		Scope:        r.Scope(),
+		Validators:   make([]string, 0, len(r.validators)),
+		OnlyIf:       make([]string, 0, len(r.onlyIf)),
+		IncludePaths: r.includePaths,
+		ExcludePaths: r.excludePaths,
+		Source:       r.source,
 	}
 	for _, v := range r.validators {
 		out.Validators = append(out.Validators, validatorTextWithScope(v, r.Scope()))
@@ -108,25 +210,53 @@ func (r *ValidationRule) MarshalYAML() (interface{}, error) {
 }
 
 func validatorTextWithScope(v ValidatorWithDetails, scope config.ValidationScope) string {
+	return scopedText(v.String(), scope)
+}
+
+func scopedText(text string, scope config.ValidationScope) string {
 	scopeText := string(scope)
 	if scope == config.AllRulesScope {
 		scopeText = "Rule"
 	}
-	return fmt.Sprintf("%s %s", scopeText, v.String())
+	return fmt.Sprintf("%s %s", scopeText, text)
+}
+
+func operand(v ValidatorWithDetails) validator.Operand {
+	if w, ok := v.(*validatorWithAdditionalDetails); ok {
+		return validator.Operand{Validator: w.Validator, Name: w.name, AdditionalDetails: w.additionalDetails}
+	}
+	return validator.Operand{Validator: v, Name: v.Name(), AdditionalDetails: v.AdditionalDetails()}
+}
+
+// validationText describes the validator, operands of the logical operators are described in the children.
+// If the scope is empty, scope of each validator is used.
+func validationText(v validator.Operand, scope config.ValidationScope, depth int) report.ValidationText {
+	logical, ok := v.Validator.(*validator.Logical)
+	if !ok {
+		if scope == "" {
+			return report.ValidationText{Text: scopedText(v.String(), v.Scope()), Depth: depth}
+		}
+		return report.ValidationText{Text: scopedText(v.String(), scope), Depth: depth}
+	}
+	text := report.ValidationText{Text: logical.Description(), Depth: depth}
+	for _, operand := range logical.Operands {
+		text.Children = append(text.Children, validationText(operand, scope, depth+1))
+	}
+	return text
 }
 
-func (r *ValidationRule) ValidationTexts() []string {
-	validationTexts := make([]string, 0, len(r.validators))
+func (r *ValidationRule) ValidationTexts() []report.ValidationText {
+	validationTexts := make([]report.ValidationText, 0, len(r.validators))
 	for _, v := range r.validators {
-		validationTexts = append(validationTexts, validatorTextWithScope(v, r.Scope()))
+		validationTexts = append(validationTexts, validationText(operand(v), r.Scope(), 0))
 	}
 	return validationTexts
 }
 
-func (r *ValidationRule) OnlyIfValidationTexts() []string {
-	validationTexts := make([]string, 0, len(r.onlyIf))
+func (r *ValidationRule) OnlyIfValidationTexts() []report.ValidationText {
+	validationTexts := make([]report.ValidationText, 0, len(r.onlyIf))
 	for _, v := range r.onlyIf {
-		validationTexts = append(validationTexts, validatorTextWithScope(v, validator.Scope(v.Name())))
+		validationTexts = append(validationTexts, validationText(operand(v), "", 0))
 	}
 	return validationTexts
 }
@@ -0,0 +1,59 @@
+package validationrule
+
+import (
+	"testing"
+
+	"github.com/fusakla/promruval/v3/pkg/config"
+	"github.com/fusakla/promruval/v3/pkg/prometheus"
+	"github.com/fusakla/promruval/v3/pkg/unmarshaler"
+	"github.com/prometheus/prometheus/model/rulefmt"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAppliesToFile(t *testing.T) {
+	tests := []struct {
+		name         string
+		includePaths []string
+		excludePaths []string
+		filePath     string
+		expected     bool
+	}{
+		{name: "noPaths", filePath: "rules/foo.yaml", expected: true},
+		{name: "included", includePaths: []string{"teams/*/slo/**"}, filePath: "teams/foo/slo/bar/rules.yaml", expected: true},
+		{name: "includedWithDotPrefix", includePaths: []string{"teams/*/slo/**"}, filePath: "./teams/foo/slo/rules.yaml", expected: true},
+		{name: "notIncluded", includePaths: []string{"teams/*/slo/**"}, filePath: "teams/foo/rules.yaml", expected: false},
+		{name: "anyOfIncluded", includePaths: []string{"foo/**", "bar/**"}, filePath: "bar/rules.yaml", expected: true},
+		{name: "excluded", excludePaths: []string{"**/*_test.yaml"}, filePath: "rules/foo_test.yaml", expected: false},
+		{name: "includedButExcluded", includePaths: []string{"teams/**"}, excludePaths: []string{"teams/legacy/**"}, filePath: "teams/legacy/rules.yaml", expected: false},
+		{name: "inMemory", includePaths: []string{"teams/**"}, filePath: "<in-memory>", expected: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule := New("test", config.AllRulesScope)
+			rule.SetPaths(tt.includePaths, tt.excludePaths)
+			assert.Equal(t, tt.expected, rule.AppliesToFile(tt.filePath))
+		})
+	}
+}
+
+type testValidator struct{}
+
+func (v *testValidator) String() string {
+	return "test"
+}
+
+func (v *testValidator) Validate(unmarshaler.RuleGroup, rulefmt.Rule, *prometheus.Client) []error {
+	return nil
+}
+
+func TestAddValidator(t *testing.T) {
+	rule := New("test", config.AllRulesScope)
+	rule.AddValidator(&testValidator{}, "")
+	rule.AddNamedValidator("custom", &testValidator{}, "")
+	rule.AddOnlyIfValidator(&testValidator{}, "")
+	rule.AddNamedOnlyIfValidator("custom", &testValidator{}, "")
+	assert.Equal(t, "testValidator", rule.Validators()[0].Name())
+	assert.Equal(t, "custom", rule.Validators()[1].Name())
+	assert.Equal(t, "testValidator", rule.OnlyIf()[0].Name())
+	assert.Equal(t, "custom", rule.OnlyIf()[1].Name())
+}
@@ -2,6 +2,7 @@ package validator
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/url"
 	"regexp"
@@ -14,9 +15,10 @@ import (
 	"github.com/prometheus/prometheus/model/rulefmt"
 	"github.com/prometheus/prometheus/promql"
 	"github.com/prometheus/prometheus/template"
+	log "github.com/sirupsen/logrus"
 )
 
-func newForIsNotLongerThan(unmarshal unmarshalParamsFunc) (Validator, error) {
+func newForIsNotLongerThan(unmarshal UnmarshalParamsFunc) (Validator, error) {
 	params := struct {
 		Limit model.Duration `yaml:"limit"`
 	}{}
@@ -44,7 +46,7 @@ func (h forIsNotLongerThan) Validate(_ unmarshaler.RuleGroup, rule rulefmt.Rule,
 	return nil
 }
 
-func newKeepFiringForIsNotLongerThan(unmarshal unmarshalParamsFunc) (Validator, error) {
+func newKeepFiringForIsNotLongerThan(unmarshal UnmarshalParamsFunc) (Validator, error) {
 	params := struct {
 		Limit model.Duration `yaml:"limit"`
 	}{}
@@ -69,7 +71,7 @@ func (h keepFiringForIsNotLongerThan) Validate(_ unmarshaler.RuleGroup, rule rul
 	return nil
 }
 
-func newValidateLabelTemplates(unmarshal unmarshalParamsFunc) (Validator, error) {
+func newValidateLabelTemplates(unmarshal UnmarshalParamsFunc) (Validator, error) {
 	params := struct{}{}
 	if err := unmarshal(&params); err != nil {
 		return nil, err
@@ -101,7 +103,7 @@ func (h validateLabelTemplates) Validate(_ unmarshaler.RuleGroup, rule rulefmt.R
 	return errs
 }
 
-func newAlertNameMatchesRegexp(unmarshal unmarshalParamsFunc) (Validator, error) {
+func newAlertNameMatchesRegexp(unmarshal UnmarshalParamsFunc) (Validator, error) {
 	params := struct {
 		Regexp   RegexpForbidEmpty `yaml:"regexp"`
 		Negative bool              `yaml:"negative"`
@@ -131,3 +133,142 @@ func (h alertNameMatchesRegexp) Validate(_ unmarshaler.RuleGroup, rule rulefmt.R
 	}
 	return errs
 }
+
+// alertSimulation summarizes how the alert would have fired in the past.
+type alertSimulation struct {
+	firings        int
+	firingDuration time.Duration
+	longestFiring  time.Duration
+	series         int
+}
+
+func (s *alertSimulation) addFiring(firedAt, resolvedAt time.Time) {
+	duration := resolvedAt.Sub(firedAt)
+	s.firings++
+	s.firingDuration += duration
+	s.longestFiring = max(s.longestFiring, duration)
+}
+
+// simulateAlert applies the `for` and `keep_firing_for` semantics of the Prometheus rule evaluation to the range query result with the given step.
+// Each sample means the alert expression returned the series at the time, missing samples mean the series was absent.
+// Firing which did not resolve until the end of the result lasts until the evaluation after the last sample.
+func simulateAlert(matrix model.Matrix, step, forDuration, keepFiringFor time.Duration) alertSimulation {
+	// Alert is resolved at the first evaluation when it is absent for at least the keep_firing_for.
+	keepFiringSteps := (keepFiringFor + step - 1) / step
+	simulation := alertSimulation{}
+	for _, series := range matrix {
+		if len(series.Values) == 0 {
+			continue
+		}
+		firedSeries := false
+		firing := false
+		var activeSince, firedAt, lastActive time.Time
+		for i, sample := range series.Values {
+			ts := sample.Timestamp.Time()
+			if i == 0 || ts.Sub(lastActive) > step {
+				absentSince := lastActive.Add(step)
+				if firing {
+					if resolvedAt := absentSince.Add(keepFiringSteps * step); resolvedAt.Before(ts) {
+						simulation.addFiring(firedAt, resolvedAt)
+						firing = false
+					}
+				}
+				if !firing {
+					activeSince = ts
+				}
+			}
+			if !firing && ts.Sub(activeSince) >= forDuration {
+				firing = true
+				firedSeries = true
+				firedAt = ts
+			}
+			lastActive = ts
+		}
+		if firing {
+			simulation.addFiring(firedAt, lastActive.Add(step))
+		}
+		if firedSeries {
+			simulation.series++
+		}
+	}
+	return simulation
+}
+
+func newAlertFiringHistory(unmarshal UnmarshalParamsFunc) (Validator, error) {
+	params := struct {
+		Window            model.Duration `yaml:"window"`
+		Step              model.Duration `yaml:"step"`
+		MaxFirings        int            `yaml:"maxFirings"`
+		MaxFiringDuration model.Duration `yaml:"maxFiringDuration"`
+	}{
+		Window: model.Duration(7 * 24 * time.Hour),
+	}
+	if err := unmarshal(&params); err != nil {
+		return nil, err
+	}
+	if params.Window <= 0 {
+		return nil, fmt.Errorf("`window` must be greater than 0")
+	}
+	if params.Step < 0 || params.MaxFirings < 0 || params.MaxFiringDuration < 0 {
+		return nil, fmt.Errorf("`step`, `maxFirings` and `maxFiringDuration` cannot be negative")
+	}
+	return &alertFiringHistory{
+		window:            time.Duration(params.Window),
+		step:              time.Duration(params.Step),
+		maxFirings:        params.MaxFirings,
+		maxFiringDuration: time.Duration(params.MaxFiringDuration),
+	}, nil
+}
+
+type alertFiringHistory struct {
+	window            time.Duration
+	step              time.Duration
+	maxFirings        int
+	maxFiringDuration time.Duration
+}
+
+func (h alertFiringHistory) String() string {
+	if h.maxFirings == 0 && h.maxFiringDuration == 0 {
+		return fmt.Sprintf("firing history in the last `%s` simulated on the live Prometheus instance is logged", model.Duration(h.window))
+	}
+	var limits []string
+	if h.maxFirings > 0 {
+		limits = append(limits, fmt.Sprintf("more than %d times", h.maxFirings))
+	}
+	if h.maxFiringDuration > 0 {
+		limits = append(limits, fmt.Sprintf("for more than `%s` in total", model.Duration(h.maxFiringDuration)))
+	}
+	return fmt.Sprintf("would not have fired %s in the last `%s` when simulated on the live Prometheus instance", strings.Join(limits, " or "), model.Duration(h.window))
+}
+
+func (h alertFiringHistory) Validate(group unmarshaler.RuleGroup, rule rulefmt.Rule, prometheusClient *prometheus.Client) []error {
+	if prometheusClient == nil {
+		log.Error("missing the `prometheus` section of configuration for querying prometheus, skipping check that requires it...")
+		return nil
+	}
+	step := h.step
+	if step == 0 {
+		step = time.Duration(group.Interval)
+	}
+	if step == 0 {
+		step = time.Minute
+	}
+	matrix, err := prometheusClient.QueryRange(rule.Expr, group.SourceTenants, h.window, step)
+	if err != nil {
+		return []error{err}
+	}
+	simulation := simulateAlert(matrix, step, time.Duration(rule.For), time.Duration(rule.KeepFiringFor))
+	summary := fmt.Sprintf("alert would have fired %d times for %d series in the last %s, firing for %s in total and %s at most at once", simulation.firings, simulation.series, model.Duration(h.window), model.Duration(simulation.firingDuration), model.Duration(simulation.longestFiring))
+	if simulation.firings == 0 {
+		summary = fmt.Sprintf("alert would not have fired in the last %s", model.Duration(h.window))
+	}
+	if h.maxFirings == 0 && h.maxFiringDuration == 0 {
+		// Without any limits the history is only reported, so it does not fail the validation.
+		log.WithFields(log.Fields{"group": group.Name, "alert": rule.Alert}).Info(summary)
+		return nil
+	}
+	if h.maxFirings > 0 && simulation.firings > h.maxFirings || h.maxFiringDuration > 0 && simulation.firingDuration > h.maxFiringDuration {
+		return []error{errors.New(summary)}
+	}
+	return nil
+}
@@ -0,0 +1,142 @@
+package validator
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"text/template"
+	"time"
+
+	"github.com/fusakla/promruval/v3/pkg/prometheus"
+	"github.com/fusakla/promruval/v3/pkg/unmarshaler"
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/types"
+	"github.com/google/cel-go/common/types/ref"
+	"github.com/prometheus/prometheus/model/rulefmt"
+)
+
+// celExpressionHelper wraps function returning list of strings from a PromQL expression, so it can be used in the CEL expression.
+func celExpressionHelper(name string, f func(expr string) ([]string, error)) cel.EnvOption {
+	return cel.Function(name,
+		cel.Overload(name+"_string", []*cel.Type{cel.StringType}, cel.ListType(cel.StringType),
+			cel.UnaryBinding(func(arg ref.Val) ref.Val {
+				expr, ok := arg.Value().(string)
+				if !ok {
+					return types.NewErr("%s: expected string argument, got %s", name, arg.Type())
+				}
+				res, err := f(expr)
+				if err != nil {
+					return types.NewErr("%s: %s", name, err)
+				}
+				return types.DefaultTypeAdapter.NativeToValue(res)
+			}),
+		),
+	)
+}
+
+func newCelEnv() (*cel.Env, error) {
+	return cel.NewEnv(
+		cel.OptionalTypes(),
+		cel.Variable("rule", cel.MapType(cel.StringType, cel.DynType)),
+		cel.Variable("group", cel.MapType(cel.StringType, cel.DynType)),
+		celExpressionHelper("metrics", func(expr string) ([]string, error) {
+			metrics, err := getExpressionMetrics(expr)
+			if err != nil {
+				return nil, err
+			}
+			names := make([]string, 0, len(metrics))
+			for _, m := range metrics {
+				names = append(names, m.Name)
+			}
+			return names, nil
+		}),
+		celExpressionHelper("labels", getExpressionUsedLabels),
+		celExpressionHelper("selectors", getExpressionSelectors),
+	)
+}
+
+func celActivation(group unmarshaler.RuleGroup, rule rulefmt.Rule) map[string]any {
+	return map[string]any{
+		"rule": map[string]any{
+			"name":            ruleName(rule),
+			"type":            ruleType(rule),
+			"expr":            rule.Expr,
+			"for":             time.Duration(rule.For),
+			"keep_firing_for": time.Duration(rule.KeepFiringFor),
+			"labels":          nonNilMap(rule.Labels),
+			"annotations":     nonNilMap(rule.Annotations),
+		},
+		"group": map[string]any{
+			"name":           group.Name,
+			"interval":       time.Duration(group.Interval),
+			"query_offset":   time.Duration(group.QueryOffset),
+			"limit":          group.Limit,
+			"source_tenants": nonNilSlice(group.SourceTenants),
+		},
+	}
+}
+
+func newCelExpression(unmarshal UnmarshalParamsFunc) (Validator, error) {
+	params := struct {
+		Expression string `yaml:"expression"`
+		Message    string `yaml:"message"`
+	}{}
+	if err := unmarshal(&params); err != nil {
+		return nil, err
+	}
+	if params.Expression == "" {
+		return nil, fmt.Errorf("missing expression")
+	}
+	env, err := newCelEnv()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create CEL environment: %w", err)
+	}
+	ast, issues := env.Compile(params.Expression)
+	if issues != nil && issues.Err() != nil {
+		return nil, fmt.Errorf("invalid CEL expression `%s`: %w", params.Expression, issues.Err())
+	}
+	if ast.OutputType() != cel.BoolType {
+		return nil, fmt.Errorf("CEL expression `%s` must evaluate to bool, got %s", params.Expression, ast.OutputType())
+	}
+	program, err := env.Program(ast)
+	if err != nil {
+		return nil, fmt.Errorf("invalid CEL expression `%s`: %w", params.Expression, err)
+	}
+	var messageTemplate *template.Template
+	if params.Message != "" {
+		messageTemplate, err = template.New("message").Option("missingkey=zero").Parse(params.Message)
+		if err != nil {
+			return nil, fmt.Errorf("invalid message template: %w", err)
+		}
+	}
+	return &celExpression{expression: params.Expression, program: program, message: messageTemplate}, nil
+}
+
+type celExpression struct {
+	expression string
+	program    cel.Program
+	message    *template.Template
+}
+
+func (h celExpression) String() string {
+	return fmt.Sprintf("satisfies the CEL expression `%s`", h.expression)
+}
+
+func (h celExpression) Validate(group unmarshaler.RuleGroup, rule rulefmt.Rule, _ *prometheus.Client) []error {
+	activation := celActivation(group, rule)
+	out, _, err := h.program.Eval(activation)
+	if err != nil {
+		return []error{fmt.Errorf("failed to evaluate CEL expression `%s`: %w", h.expression, err)}
+	}
+	if valid, ok := out.Value().(bool); ok && valid {
+		return nil
+	}
+	if h.message == nil {
+		return []error{fmt.Errorf("does not satisfy the CEL expression `%s`", h.expression)}
+	}
+	var msg bytes.Buffer
+	if err := h.message.Execute(&msg, activation); err != nil {
+		return []error{fmt.Errorf("does not satisfy the CEL expression `%s`, failed to render the message: %w", h.expression, err)}
+	}
+	return []error{errors.New(msg.String())}
+}
@@ -4,17 +4,27 @@ import (
 	"errors"
 	"fmt"
 	"maps"
+	"slices"
+	"sync"
 
 	"github.com/fusakla/promruval/v3/pkg/config"
 	"github.com/fusakla/promruval/v3/pkg/unmarshaler"
 	"github.com/prometheus/prometheus/model/rulefmt"
 )
 
-type unmarshalParamsFunc func(interface{}) error
+// UnmarshalParamsFunc unmarshals the validator `params` from the config into the given struct, unknown fields are not allowed.
+type UnmarshalParamsFunc func(interface{}) error
 
-type validatorCreator func(unmarshal unmarshalParamsFunc) (Validator, error)
+// RelativePathsResolver can be implemented by the validator params containing paths relative to the config file.
+// The ResolveRelativePaths is called right after the params are unmarshaled with the directory of the config file.
+type RelativePathsResolver interface {
+	ResolveRelativePaths(baseDir string) error
+}
+
+// Creator creates new validator from its params, it must always call the unmarshal function exactly once even if the validator has no params.
+type Creator func(unmarshal UnmarshalParamsFunc) (Validator, error)
 
-var registeredUniversalRuleValidators = map[string]validatorCreator{
+var registeredUniversalRuleValidators = map[string]Creator{
 	// Labels
 	"hasLabels":            newHasLabels,
 	"doesNotHaveLabels":    newDoesNotHaveLabels,
@@ -39,6 +49,7 @@ var registeredUniversalRuleValidators = map[string]validatorCreator{
 	"expressionCanBeEvaluated":                             newExpressionCanBeEvaluated,
 	"expressionUsesExistingLabels":                         newExpressionUsesExistingLabels,
 	"expressionSelectorsMatchesAnything":                   newExpressionSelectorsMatchesAnything,
+	"expressionDoesNotSelectHighCardinalityMetrics":        newExpressionDoesNotSelectHighCardinalityMetrics,
 	"expressionWithNoMetricName":                           newExpressionWithNoMetricName,
 	"expressionIsWellFormatted":                            newExpressionIsWellFormatted,
 	"expressionUsesUnderscoresInLargeNumbers":              newExpressionUsesUnderscoresInLargeNumbers,
@@ -53,17 +64,23 @@ var registeredUniversalRuleValidators = map[string]validatorCreator{
 	// Other
 	"hasSourceTenantsForMetrics": newHasSourceTenantsForMetrics,
 	"doesNotContainTypos":        newDoesNotContainTypos,
+	"celExpression":              newCelExpression,
+	"regoPolicy":                 newRegoPolicy,
+	"exec":                       newExec,
+	"wasmPlugin":                 newWasmPlugin,
 }
 
-var registeredRecordingRuleValidators = map[string]validatorCreator{
+var registeredRecordingRuleValidators = map[string]Creator{
 	"recordedMetricNameMatchesRegexp":      newRecordedMetricNameMatchesRegexp,
 	"recordedMetricNameDoesNotMatchRegexp": newRecordedMetricNameDoesNotMatchRegexp,
+	"recordingRuleOutputCardinalityBelow":  newRecordingRuleOutputCardinalityBelow,
 }
 
-var registeredAlertValidators = map[string]validatorCreator{
+var registeredAlertValidators = map[string]Creator{
 	"forIsNotLongerThan":           newForIsNotLongerThan,
 	"keepFiringForIsNotLongerThan": newKeepFiringForIsNotLongerThan,
 	"alertNameMatchesRegexp":       newAlertNameMatchesRegexp,
+	"alertFiringHistory":           newAlertFiringHistory,
 
 	"validateAnnotationTemplates": newValidateAnnotationTemplates,
 	"annotationIsValidPromQL":     newAnnotationIsValidPromQL,
@@ -76,7 +93,7 @@ var registeredAlertValidators = map[string]validatorCreator{
 	"validateLabelTemplates":      newValidateLabelTemplates,
 }
 
-var registeredGroupValidators = map[string]validatorCreator{
+var registeredGroupValidators = map[string]Creator{
 	"hasAllowedSourceTenants":         newHasAllowedSourceTenants,
 	"hasAllowedEvaluationInterval":    newHasAllowedEvaluationInterval,
 	"hasValidPartialResponseStrategy": newHasValidPartialResponseStrategy,
@@ -84,15 +101,22 @@ var registeredGroupValidators = map[string]validatorCreator{
 	"hasAllowedLimit":                 newHasAllowedLimit,
 	"groupNameMatchesRegexp":          newGroupNameMatchesRegexp,
 	"hasAllowedQueryOffset":           newHasAllowedQueryOffset,
+	"groupEvaluationCostWithinBudget": newGroupEvaluationCostWithinBudget,
 }
 
 var (
-	alertValidators         = map[string]validatorCreator{}
-	recordingRuleValidators = map[string]validatorCreator{}
-	allValidators           = map[string]validatorCreator{}
+	alertValidators         = map[string]Creator{}
+	recordingRuleValidators = map[string]Creator{}
+	allValidators           = map[string]Creator{}
+
+	registryMtx sync.RWMutex
 )
 
 func init() {
+	updateScopedValidators()
+}
+
+func updateScopedValidators() {
 	maps.Copy(alertValidators, registeredUniversalRuleValidators)
 	maps.Copy(alertValidators, registeredAlertValidators)
 
@@ -104,7 +128,54 @@ func init() {
 	maps.Copy(allValidators, registeredGroupValidators)
 }
 
+// RegisterValidator registers a custom validator, so it can be used in the config the same way as the built-in ones.
+// The scope must be one of Alert, Recording rule, All rules (for validators applicable to both alerts and recording rules) or Group.
+// It is meant to be called before loading the config, typically from the init function of the package defining the validator.
+func RegisterValidator(scope config.ValidationScope, name string, creator Creator) error {
+	if name == "" {
+		return fmt.Errorf("validator name cannot be empty")
+	}
+	if creator == nil {
+		return fmt.Errorf("validator `%s` creator cannot be nil", name)
+	}
+	registryMtx.Lock()
+	defer registryMtx.Unlock()
+	if _, ok := allValidators[name]; ok {
+		return fmt.Errorf("validator `%s` is already registered", name)
+	}
+	if slices.Contains(logicalOperators, name) {
+		return fmt.Errorf("validator name `%s` is reserved for the logical operator", name)
+	}
+	switch scope {
+	case config.AlertScope:
+		registeredAlertValidators[name] = creator
+	case config.RecordingRuleScope:
+		registeredRecordingRuleValidators[name] = creator
+	case config.AllRulesScope:
+		registeredUniversalRuleValidators[name] = creator
+	case config.GroupScope:
+		registeredGroupValidators[name] = creator
+	default:
+		return fmt.Errorf("invalid scope `%s` of validator `%s`", scope, name)
+	}
+	updateScopedValidators()
+	return nil
+}
+
+// MustRegisterValidator is like RegisterValidator but panics on error.
+func MustRegisterValidator(scope config.ValidationScope, name string, creator Creator) {
+	if err := RegisterValidator(scope, name, creator); err != nil {
+		panic(err)
+	}
+}
+
 func NewFromConfig(scope config.ValidationScope, validatorConfig config.ValidatorConfig) (Validator, error) {
+	if validatorConfig.ValidatorType == "" && len(validatorConfig.Operands()) > 0 {
+		return newLogical(scope, validatorConfig.Name(), validatorConfig.Operands())
+	}
+	if slices.Contains(logicalOperators, validatorConfig.ValidatorType) {
+		return nil, fmt.Errorf("`%s` is a logical operator, use it in place of the `type` key", validatorConfig.ValidatorType)
+	}
 	factory, ok := creator(scope, validatorConfig.ValidatorType)
 	if !ok {
 		return nil, fmt.Errorf("unknown validator type `%s`", validatorConfig.ValidatorType)
@@ -112,7 +183,13 @@ func NewFromConfig(scope config.ValidationScope, validatorConfig config.Validato
 	unmarshaled := false
 	validator, err := factory(func(v interface{}) error {
 		unmarshaled = true
-		return unmarshaler.UnmarshalNodeToStruct(&validatorConfig.Params, v)
+		if err := unmarshaler.UnmarshalNodeToStruct(&validatorConfig.Params, v); err != nil {
+			return err
+		}
+		if resolver, ok := v.(RelativePathsResolver); ok {
+			return resolver.ResolveRelativePaths(validatorConfig.BaseDir)
+		}
+		return nil
 	})
 	if !unmarshaled {
 		err = errors.Join(err, fmt.Errorf("BUG: unmarshal() not called when creating validator type %q", validatorConfig.ValidatorType))
@@ -120,8 +197,10 @@ func NewFromConfig(scope config.ValidationScope, validatorConfig config.Validato
 	return validator, err
 }
 
-func creator(scope config.ValidationScope, name string) (validatorCreator, bool) {
-	var validators map[string]validatorCreator
+func creator(scope config.ValidationScope, name string) (Creator, bool) {
+	registryMtx.RLock()
+	defer registryMtx.RUnlock()
+	var validators map[string]Creator
 	switch scope {
 	case config.AlertScope:
 		validators = alertValidators
@@ -140,7 +219,7 @@ func creator(scope config.ValidationScope, name string) (validatorCreator, bool)
 
 func KnownValidators(scope config.ValidationScope, validatorNames []string) error {
 	for _, validatorName := range validatorNames {
-		if _, ok := creator(scope, validatorName); !ok {
+		if _, ok := creator(scope, validatorName); !ok && !slices.Contains(logicalOperators, validatorName) {
 			return fmt.Errorf("unknown validator `%s` for given validation rule scope %s, see the docs/validations.md for the complete list and allowed scopes", validatorName, scope)
 		}
 	}
@@ -148,7 +227,9 @@ func KnownValidators(scope config.ValidationScope, validatorNames []string) erro
 }
 
 func Scope(validatorName string) config.ValidationScope {
-	for scope, validators := range map[config.ValidationScope]map[string]validatorCreator{
+	registryMtx.RLock()
+	defer registryMtx.RUnlock()
+	for scope, validators := range map[config.ValidationScope]map[string]Creator{
 		config.AlertScope:         registeredAlertValidators,
 		config.RecordingRuleScope: registeredRecordingRuleValidators,
 		config.GroupScope:         registeredGroupValidators,
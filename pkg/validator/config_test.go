@@ -4,8 +4,10 @@ import (
 	"testing"
 
 	"github.com/fusakla/promruval/v3/pkg/config"
+	"github.com/fusakla/promruval/v3/pkg/unmarshaler"
 	"github.com/prometheus/prometheus/model/rulefmt"
 	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
 )
 
 func TestScope(t *testing.T) {
@@ -101,3 +103,142 @@ func TestMatchesScope(t *testing.T) {
 		})
 	}
 }
+
+func TestRegisterValidator(t *testing.T) {
+	newCustomValidator := func(unmarshal UnmarshalParamsFunc) (Validator, error) {
+		params := struct {
+			Labels []string `yaml:"labels"`
+		}{}
+		if err := unmarshal(&params); err != nil {
+			return nil, err
+		}
+		return &hasLabels{labels: params.Labels}, nil
+	}
+
+	assert.NoError(t, RegisterValidator(config.AllRulesScope, "customHasLabels", newCustomValidator))
+	assert.Error(t, RegisterValidator(config.AlertScope, "customHasLabels", newCustomValidator), "duplicate registration must fail")
+	assert.Error(t, RegisterValidator(config.AlertScope, "hasLabels", newCustomValidator), "overriding built-in validator must fail")
+	assert.Error(t, RegisterValidator(config.AllScope, "customInvalidScope", newCustomValidator), "only specific scopes are allowed")
+	assert.NoError(t, RegisterValidator(config.GroupScope, "customGroupValidator", newCustomValidator))
+
+	assert.Equal(t, config.AllRulesScope, Scope("customHasLabels"))
+	assert.Equal(t, config.GroupScope, Scope("customGroupValidator"))
+	assert.NoError(t, KnownValidators(config.AlertScope, []string{"customHasLabels"}))
+	assert.NoError(t, KnownValidators(config.RecordingRuleScope, []string{"customHasLabels"}))
+	assert.Error(t, KnownValidators(config.AlertScope, []string{"customGroupValidator"}))
+
+	validatorConfig := config.ValidatorConfig{ValidatorType: "customHasLabels"}
+	assert.NoError(t, yaml.Unmarshal([]byte(`labels: ["foo"]`), &validatorConfig.Params))
+	v, err := NewFromConfig(config.AlertScope, validatorConfig)
+	assert.NoError(t, err)
+	assert.Len(t, v.Validate(unmarshaler.RuleGroup{}, rulefmt.Rule{}, nil), 1)
+}
+
+func TestLogicalValidators(t *testing.T) {
+	alert := func(labels map[string]string) rulefmt.Rule {
+		return rulefmt.Rule{Alert: "Foo", Expr: "up == 0", Labels: labels}
+	}
+	tests := []struct {
+		name           string
+		config         string
+		rule           rulefmt.Rule
+		expectedScope  config.ValidationScope
+		expectedErrors int
+	}{
+		{
+			name:           "anyOfPasses",
+			config:         `anyOf: [{type: hasLabels, params: {labels: ["foo"]}}, {type: hasLabels, params: {labels: ["bar"]}}]`,
+			rule:           alert(map[string]string{"bar": "1"}),
+			expectedScope:  config.AllRulesScope,
+			expectedErrors: 0,
+		},
+		{
+			name:           "anyOfFails",
+			config:         `anyOf: [{type: hasLabels, params: {labels: ["foo"]}}, {type: hasLabels, params: {labels: ["bar"]}}]`,
+			rule:           alert(nil),
+			expectedScope:  config.AllRulesScope,
+			expectedErrors: 1,
+		},
+		{
+			name:           "allOfFails",
+			config:         `allOf: [{type: hasLabels, params: {labels: ["foo"]}}, {type: hasLabels, params: {labels: ["bar"]}}]`,
+			rule:           alert(nil),
+			expectedScope:  config.AllRulesScope,
+			expectedErrors: 2,
+		},
+		{
+			name:           "notPasses",
+			config:         `not: {type: hasLabels, params: {labels: ["foo"]}}`,
+			rule:           alert(nil),
+			expectedScope:  config.AllRulesScope,
+			expectedErrors: 0,
+		},
+		{
+			name:           "notFails",
+			config:         `not: {type: hasLabels, params: {labels: ["foo"]}}`,
+			rule:           alert(map[string]string{"foo": "1"}),
+			expectedScope:  config.AllRulesScope,
+			expectedErrors: 1,
+		},
+		{
+			name:           "nested",
+			config:         `anyOf: [{not: {type: hasLabels, params: {labels: ["foo"]}}}, {allOf: [{type: hasAnnotations, params: {annotations: ["bar"]}}]}]`,
+			rule:           alert(map[string]string{"foo": "1"}),
+			expectedScope:  config.AllRulesScope,
+			expectedErrors: 1,
+		},
+		{
+			name:           "mixedScopes",
+			config:         `allOf: [{type: maxRulesPerGroup, params: {limit: 10}}, {type: forIsNotLongerThan, params: {limit: 1h}}]`,
+			rule:           alert(nil),
+			expectedScope:  config.AllRulesScope,
+			expectedErrors: 0,
+		},
+		{
+			name:           "groupScope",
+			config:         `anyOf: [{type: maxRulesPerGroup, params: {limit: 10}}, {type: groupNameMatchesRegexp, params: {regexp: "foo"}}]`,
+			rule:           alert(nil),
+			expectedScope:  config.GroupScope,
+			expectedErrors: 0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var validatorConfig config.ValidatorConfig
+			assert.NoError(t, yaml.Unmarshal([]byte(tt.config), &validatorConfig))
+			v, err := NewFromConfig(config.AllScope, validatorConfig)
+			assert.NoError(t, err)
+			logical, ok := v.(*Logical)
+			assert.True(t, ok)
+			assert.Equal(t, tt.expectedScope, logical.Scope())
+			assert.Len(t, v.Validate(unmarshaler.RuleGroup{}, tt.rule, nil), tt.expectedErrors)
+		})
+	}
+}
+
+func TestLogicalValidatorsInvalidConfig(t *testing.T) {
+	for _, c := range []string{
+		`{type: hasLabels, anyOf: [{type: hasLabels}]}`,
+		`{anyOf: []}`,
+		`{not: {type: hasLabels}, params: {labels: ["foo"]}}`,
+		`{}`,
+	} {
+		var validatorConfig config.ValidatorConfig
+		assert.Error(t, yaml.Unmarshal([]byte(c), &validatorConfig), c)
+	}
+
+	var validatorConfig config.ValidatorConfig
+	assert.NoError(t, yaml.Unmarshal([]byte(`anyOf: [{type: hasAnnotations, params: {annotations: ["foo"]}}]`), &validatorConfig))
+	_, err := NewFromConfig(config.RecordingRuleScope, validatorConfig)
+	assert.Error(t, err, "validator not allowed in the scope must fail")
+
+	validatorConfig = config.ValidatorConfig{}
+	assert.NoError(t, yaml.Unmarshal([]byte(`{type: not, params: {validator: {type: hasAnnotations, params: {annotations: ["foo"]}}}}`), &validatorConfig))
+	_, err = NewFromConfig(config.AlertScope, validatorConfig)
+	assert.ErrorContains(t, err, "`not` is a logical operator, use it in place of the `type` key")
+
+	for _, scope := range []config.ValidationScope{config.AlertScope, config.RecordingRuleScope, config.GroupScope, config.AllRulesScope, config.AllScope} {
+		assert.NoError(t, KnownValidators(scope, []string{"allOf", "anyOf", "not"}), "logical operators must be known in any scope, so they can be disabled")
+	}
+	assert.Error(t, RegisterValidator(config.AlertScope, "not", func(UnmarshalParamsFunc) (Validator, error) { return nil, nil }))
+}
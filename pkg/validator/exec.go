@@ -0,0 +1,129 @@
+package validator
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/fusakla/promruval/v3/pkg/prometheus"
+	"github.com/fusakla/promruval/v3/pkg/unmarshaler"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/model/rulefmt"
+	log "github.com/sirupsen/logrus"
+)
+
+const defaultExecTimeout = 10 * time.Second
+
+type execParams struct {
+	Command        string         `yaml:"command"`
+	Args           []string       `yaml:"args"`
+	Timeout        model.Duration `yaml:"timeout"`
+	MaxConcurrency int            `yaml:"maxConcurrency"`
+
+	resolvedCommand string
+}
+
+// ResolveRelativePaths resolves the command relative to the config file if it contains a path separator, bare names are looked up in PATH.
+// The path is made absolute, so a command in the current directory is not looked up in PATH once the `./` prefix is cleaned by the join.
+func (p *execParams) ResolveRelativePaths(baseDir string) error {
+	p.resolvedCommand = p.Command
+	if p.Command != "" && !filepath.IsAbs(p.Command) && strings.ContainsAny(p.Command, "/"+string(filepath.Separator)) {
+		resolved, err := filepath.Abs(filepath.Join(baseDir, p.Command))
+		if err != nil {
+			return fmt.Errorf("failed to resolve command path %s: %w", p.Command, err)
+		}
+		p.resolvedCommand = resolved
+	}
+	return nil
+}
+
+func newExec(unmarshal UnmarshalParamsFunc) (Validator, error) {
+	params := execParams{}
+	if err := unmarshal(&params); err != nil {
+		return nil, err
+	}
+	if params.Command == "" {
+		return nil, fmt.Errorf("missing command")
+	}
+	if params.resolvedCommand == "" {
+		if err := params.ResolveRelativePaths(""); err != nil {
+			return nil, err
+		}
+	}
+	command, err := exec.LookPath(params.resolvedCommand)
+	if err != nil {
+		return nil, fmt.Errorf("invalid command %s: %w", params.Command, err)
+	}
+	if params.Timeout < 0 {
+		return nil, fmt.Errorf("timeout must not be negative")
+	}
+	timeout := time.Duration(params.Timeout)
+	if timeout == 0 {
+		timeout = defaultExecTimeout
+	}
+	if params.MaxConcurrency < 0 {
+		return nil, fmt.Errorf("maxConcurrency must not be negative")
+	}
+	maxConcurrency := params.MaxConcurrency
+	if maxConcurrency == 0 {
+		maxConcurrency = runtime.NumCPU()
+	}
+	return &execCommand{
+		name:      params.Command,
+		command:   command,
+		args:      params.Args,
+		timeout:   timeout,
+		semaphore: make(chan struct{}, maxConcurrency),
+	}, nil
+}
+
+type execCommand struct {
+	name      string
+	command   string
+	args      []string
+	timeout   time.Duration
+	semaphore chan struct{}
+}
+
+func (h execCommand) String() string {
+	return fmt.Sprintf("passes the check of the external command `%s`", strings.Join(append([]string{h.name}, h.args...), " "))
+}
+
+func (h execCommand) Validate(group unmarshaler.RuleGroup, rule rulefmt.Rule, _ *prometheus.Client) []error {
+	input, err := json.Marshal(newPolicyInput(group, rule))
+	if err != nil {
+		return []error{fmt.Errorf("failed to marshal the input of the command `%s`: %w", h.name, err)}
+	}
+
+	h.semaphore <- struct{}{}
+	defer func() { <-h.semaphore }()
+
+	ctx, cancel := context.WithTimeout(context.Background(), h.timeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, h.command, h.args...)
+	cmd.Stdin = bytes.NewReader(input)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	// Do not wait for the output of possible child processes of the killed command forever.
+	cmd.WaitDelay = time.Second
+	if err := cmd.Run(); err != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return []error{fmt.Errorf("command `%s` timed out after %s", h.name, h.timeout)}
+		}
+		return []error{fmt.Errorf("command `%s` failed: %w, stderr: %s", h.name, err, strings.TrimSpace(stderr.String()))}
+	}
+
+	errs, err := policyOutputErrors(stdout.Bytes(), log.Fields{"command": h.name, "group": group.Name, "rule": ruleName(rule)})
+	if err != nil {
+		return []error{fmt.Errorf("invalid output of the command `%s`: %w", h.name, err)}
+	}
+	return errs
+}
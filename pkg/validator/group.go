@@ -5,14 +5,16 @@ import (
 	"regexp"
 	"slices"
 	"strings"
+	"time"
 
 	"github.com/fusakla/promruval/v3/pkg/prometheus"
 	"github.com/fusakla/promruval/v3/pkg/unmarshaler"
 	"github.com/prometheus/common/model"
 	"github.com/prometheus/prometheus/model/rulefmt"
+	log "github.com/sirupsen/logrus"
 )
 
-func newHasAllowedSourceTenants(unmarshal unmarshalParamsFunc) (Validator, error) {
+func newHasAllowedSourceTenants(unmarshal UnmarshalParamsFunc) (Validator, error) {
 	params := struct {
 		AllowedSourceTenants []string `yaml:"allowedSourceTenants"`
 	}{}
@@ -43,7 +45,7 @@ func (h hasAllowedSourceTenants) Validate(group unmarshaler.RuleGroup, _ rulefmt
 	return []error{fmt.Errorf("group has invalid source_tenants: `%s`", strings.Join(invalidTenants, "`,`"))}
 }
 
-func newHasAllowedEvaluationInterval(unmarshal unmarshalParamsFunc) (Validator, error) {
+func newHasAllowedEvaluationInterval(unmarshal UnmarshalParamsFunc) (Validator, error) {
 	params := struct {
 		Minimum   model.Duration `yaml:"minimum"`
 		Maximum   model.Duration `yaml:"maximum"`
@@ -96,7 +98,7 @@ func (h hasAllowedEvaluationInterval) Validate(group unmarshaler.RuleGroup, _ ru
 	return []error{}
 }
 
-func newHasValidPartialResponseStrategy(unmarshal unmarshalParamsFunc) (Validator, error) {
+func newHasValidPartialResponseStrategy(unmarshal UnmarshalParamsFunc) (Validator, error) {
 	params := struct {
 		MustBeSet bool `yaml:"mustBeSet"`
 	}{}
@@ -133,7 +135,7 @@ func (h hasValidPartialResponseStrategy) Validate(group unmarshaler.RuleGroup, _
 	return []error{}
 }
 
-func newMaxRulesPerGroup(unmarshal unmarshalParamsFunc) (Validator, error) {
+func newMaxRulesPerGroup(unmarshal UnmarshalParamsFunc) (Validator, error) {
 	params := struct {
 		Limit int `yaml:"limit"`
 	}{}
@@ -158,7 +160,7 @@ func (h maxRulesPerGroup) Validate(group unmarshaler.RuleGroup, _ rulefmt.Rule,
 	return []error{}
 }
 
-func newHasAllowedLimit(unmarshal unmarshalParamsFunc) (Validator, error) {
+func newHasAllowedLimit(unmarshal UnmarshalParamsFunc) (Validator, error) {
 	params := struct {
 		Limit int `yaml:"limit"`
 	}{}
@@ -186,7 +188,7 @@ func (h hasAllowedLimit) Validate(group unmarshaler.RuleGroup, _ rulefmt.Rule, _
 	return []error{}
 }
 
-func newHasAllowedQueryOffset(unmarshal unmarshalParamsFunc) (Validator, error) {
+func newHasAllowedQueryOffset(unmarshal UnmarshalParamsFunc) (Validator, error) {
 	params := struct {
 		Minimum model.Duration `yaml:"minimum"`
 		Maximum model.Duration `yaml:"maximum"`
@@ -225,7 +227,7 @@ func (h hasAllowedQueryOffset) Validate(group unmarshaler.RuleGroup, _ rulefmt.R
 	return []error{}
 }
 
-func newGroupNameMatchesRegexp(unmarshal unmarshalParamsFunc) (Validator, error) {
+func newGroupNameMatchesRegexp(unmarshal UnmarshalParamsFunc) (Validator, error) {
 	params := struct {
 		Regexp   RegexpEmptyDefault `yaml:"regexp"`
 		Negative bool               `yaml:"negative"`
@@ -255,3 +257,94 @@ func (h groupNameMatchesRegexp) Validate(group unmarshaler.RuleGroup, _ rulefmt.
 	}
 	return errs
 }
+
+func newGroupEvaluationCostWithinBudget(unmarshal UnmarshalParamsFunc) (Validator, error) {
+	params := struct {
+		MaxIntervalFraction float64        `yaml:"maxIntervalFraction"`
+		MaxSamples          int64          `yaml:"maxSamples"`
+		DefaultInterval     model.Duration `yaml:"defaultInterval"`
+	}{
+		MaxIntervalFraction: 0.5,
+		DefaultInterval:     model.Duration(time.Minute),
+	}
+	if err := unmarshal(&params); err != nil {
+		return nil, err
+	}
+	if params.MaxIntervalFraction <= 0 || params.MaxIntervalFraction > 1 {
+		return nil, fmt.Errorf("`maxIntervalFraction` must be greater than 0 and at most 1")
+	}
+	if params.MaxSamples < 0 {
+		return nil, fmt.Errorf("`maxSamples` must be greater than or equal to 0")
+	}
+	if params.DefaultInterval <= 0 {
+		return nil, fmt.Errorf("`defaultInterval` must be greater than 0")
+	}
+	return &groupEvaluationCostWithinBudget{
+		maxIntervalFraction: params.MaxIntervalFraction,
+		maxSamples:          params.MaxSamples,
+		defaultInterval:     time.Duration(params.DefaultInterval),
+	}, nil
+}
+
+type groupEvaluationCostWithinBudget struct {
+	maxIntervalFraction float64
+	maxSamples          int64
+	defaultInterval     time.Duration
+}
+
+func (h groupEvaluationCostWithinBudget) String() string {
+	text := fmt.Sprintf("evaluation of all the rules in the live Prometheus instance takes at most %.0f%% of the evaluation interval (`%s` if not set)", h.maxIntervalFraction*100, model.Duration(h.defaultInterval))
+	if h.maxSamples > 0 {
+		text += fmt.Sprintf(" and loads at most %d samples", h.maxSamples)
+	}
+	return text
+}
+
+func (h groupEvaluationCostWithinBudget) Validate(group unmarshaler.RuleGroup, _ rulefmt.Rule, prometheusClient *prometheus.Client) []error {
+	if prometheusClient == nil {
+		log.Error("missing the `prometheus` section of configuration for querying prometheus, skipping check that requires it...")
+		return nil
+	}
+	interval := time.Duration(group.Interval)
+	if interval == 0 {
+		interval = h.defaultInterval
+	}
+	var errs []error
+	var totalDuration, slowestDuration time.Duration
+	var totalSamples int64
+	var slowestRule string
+	for _, ruleNode := range group.Rules {
+		rule := ruleNode.OriginalRule()
+		stats, err := prometheusClient.QueryStatistics(rule.Expr, group.SourceTenants)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to get evaluation cost of the rule `%s`: %w", ruleName(rule), err))
+			continue
+		}
+		// The evaluation time reported by the Prometheus does not include the network and queueing overhead of the request.
+		duration := stats.EvalDuration
+		if duration == 0 {
+			duration = stats.Duration
+		}
+		totalDuration += duration
+		totalSamples += stats.Samples
+		if duration > slowestDuration {
+			slowestDuration = duration
+			slowestRule = ruleName(rule)
+		}
+	}
+	if budget := time.Duration(float64(interval) * h.maxIntervalFraction); totalDuration > budget {
+		errs = append(errs, fmt.Errorf("evaluation of the group takes %s which exceeds %.0f%% of the evaluation interval %s, the slowest rule `%s` takes %s", totalDuration, h.maxIntervalFraction*100, model.Duration(interval), slowestRule, slowestDuration))
+	}
+	if h.maxSamples > 0 && totalSamples > h.maxSamples {
+		errs = append(errs, fmt.Errorf("evaluation of the group loads %d samples exceeding the limit %d", totalSamples, h.maxSamples))
+	}
+	return errs
+}
+
+func (h groupEvaluationCostWithinBudget) WarmCache(group unmarshaler.RuleGroup, _ rulefmt.Rule, prometheusClient *prometheus.Client) error {
+	// Failed queries are cached as well, so their error does not mean the warming failed.
+	for _, ruleNode := range group.Rules {
+		_, _ = prometheusClient.QueryStatistics(ruleNode.OriginalRule().Expr, group.SourceTenants)
+	}
+	return nil
+}
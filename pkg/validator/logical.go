@@ -0,0 +1,142 @@
+package validator
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/fusakla/promruval/v3/pkg/config"
+	"github.com/fusakla/promruval/v3/pkg/prometheus"
+	"github.com/fusakla/promruval/v3/pkg/unmarshaler"
+	"github.com/prometheus/prometheus/model/rulefmt"
+)
+
+// Operand is a validator composed by the Logical validator.
+type Operand struct {
+	Validator
+	Name              string
+	AdditionalDetails string
+}
+
+// Scope returns the scope of the operand validator.
+func (o Operand) Scope() config.ValidationScope {
+	if l, ok := o.Validator.(*Logical); ok {
+		return l.Scope()
+	}
+	return Scope(o.Name)
+}
+
+func (o Operand) validate(group unmarshaler.RuleGroup, rule rulefmt.Rule, prometheusClient *prometheus.Client) []error {
+	errs := o.Validate(group, rule, prometheusClient)
+	if o.AdditionalDetails == "" {
+		return errs
+	}
+	for i, err := range errs {
+		errs[i] = fmt.Errorf("%w (%s)", err, o.AdditionalDetails)
+	}
+	return errs
+}
+
+// Logical composes other validators using one of the logical operators allOf, anyOf or not, configured in place of the validator type.
+type Logical struct {
+	Operator string
+	Operands []Operand
+}
+
+// logicalOperators are known in any scope, so they can be disabled using the comments the same way as the validators.
+var logicalOperators = []string{config.AllOfOperator, config.AnyOfOperator, config.NotOperator}
+
+func newLogical(scope config.ValidationScope, operator string, operandConfigs []config.ValidatorConfig) (*Logical, error) {
+	if len(operandConfigs) == 0 {
+		return nil, fmt.Errorf("%s: at least one validator needs to be set", operator)
+	}
+	logical := &Logical{Operator: operator}
+	for _, operandConfig := range operandConfigs {
+		if err := KnownValidators(scope, []string{operandConfig.Name()}); err != nil {
+			_ = Close(logical)
+			return nil, fmt.Errorf("%s: %w", logical.Operator, err)
+		}
+		operand, err := NewFromConfig(scope, operandConfig)
+		if err != nil {
+			_ = Close(logical)
+			return nil, fmt.Errorf("%s: %w", logical.Operator, err)
+		}
+		logical.Operands = append(logical.Operands, Operand{Validator: operand, Name: operandConfig.Name(), AdditionalDetails: operandConfig.AdditionalDetails})
+	}
+	return logical, nil
+}
+
+// Scope returns the common scope of all the operands, if they differ, the validator needs to be evaluated for each rule.
+func (h *Logical) Scope() config.ValidationScope {
+	var scope config.ValidationScope
+	for i, o := range h.Operands {
+		if i == 0 {
+			scope = o.Scope()
+			continue
+		}
+		if o.Scope() != scope {
+			return config.AllRulesScope
+		}
+	}
+	return scope
+}
+
+// Description returns the text describing the operator to be followed by the list of operands.
+func (h *Logical) Description() string {
+	switch h.Operator {
+	case config.AnyOfOperator:
+		return "ANY of the following conditions is met:"
+	case config.NotOperator:
+		return "The following condition is NOT met:"
+	}
+	return "ALL of the following conditions are met:"
+}
+
+func (h *Logical) String() string {
+	operands := make([]string, len(h.Operands))
+	for i, o := range h.Operands {
+		operands[i] = fmt.Sprintf("(%s)", o.String())
+	}
+	switch h.Operator {
+	case config.AnyOfOperator:
+		return strings.Join(operands, " OR ")
+	case config.NotOperator:
+		return "NOT " + operands[0]
+	}
+	return strings.Join(operands, " AND ")
+}
+
+func (h *Logical) Validate(group unmarshaler.RuleGroup, rule rulefmt.Rule, prometheusClient *prometheus.Client) []error {
+	switch h.Operator {
+	case config.AnyOfOperator:
+		var failures []string
+		for _, o := range h.Operands {
+			errs := o.validate(group, rule, prometheusClient)
+			if len(errs) == 0 {
+				return nil
+			}
+			failures = append(failures, fmt.Sprintf("%s: %s", o.Name, joinErrors(errs)))
+		}
+		return []error{fmt.Errorf("none of the conditions is met: %s", strings.Join(failures, "; "))}
+	case config.NotOperator:
+		o := h.Operands[0]
+		if len(o.Validate(group, rule, prometheusClient)) == 0 {
+			return []error{fmt.Errorf("%s: the condition should not be met: %s", o.Name, o.String())}
+		}
+		return nil
+	}
+	var errs []error
+	for _, o := range h.Operands {
+		for _, err := range o.validate(group, rule, prometheusClient) {
+			errs = append(errs, fmt.Errorf("%s: %w", o.Name, err))
+		}
+	}
+	return errs
+}
+
+func joinErrors(errs []error) string {
+	texts := make([]string, len(errs))
+	for i, err := range errs {
+		texts[i] = err.Error()
+	}
+	return strings.Join(texts, ", ")
+}
@@ -0,0 +1,128 @@
+package validator
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/fusakla/promruval/v3/pkg/unmarshaler"
+	"github.com/prometheus/prometheus/model/rulefmt"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	policySeverityError   = "error"
+	policySeverityWarning = "warning"
+)
+
+// policyInput is the JSON document describing the validated rule and its group, passed to the external policies.
+type policyInput struct {
+	Group policyGroup `json:"group"`
+	Rule  policyRule  `json:"rule"`
+}
+
+type policyGroup struct {
+	Name                    string   `json:"name"`
+	Interval                string   `json:"interval"`
+	QueryOffset             string   `json:"query_offset"`
+	Limit                   int      `json:"limit"`
+	SourceTenants           []string `json:"source_tenants"`
+	PartialResponseStrategy string   `json:"partial_response_strategy"`
+}
+
+type policyRule struct {
+	Name          string            `json:"name"`
+	Type          string            `json:"type"`
+	Expr          string            `json:"expr"`
+	For           string            `json:"for"`
+	KeepFiringFor string            `json:"keep_firing_for"`
+	Labels        map[string]string `json:"labels"`
+	Annotations   map[string]string `json:"annotations"`
+}
+
+func newPolicyInput(group unmarshaler.RuleGroup, rule rulefmt.Rule) policyInput {
+	return policyInput{
+		Group: policyGroup{
+			Name:                    group.Name,
+			Interval:                group.Interval.String(),
+			QueryOffset:             group.QueryOffset.String(),
+			Limit:                   group.Limit,
+			SourceTenants:           nonNilSlice(group.SourceTenants),
+			PartialResponseStrategy: group.PartialResponseStrategy,
+		},
+		Rule: policyRule{
+			Name:          ruleName(rule),
+			Type:          ruleType(rule),
+			Expr:          rule.Expr,
+			For:           rule.For.String(),
+			KeepFiringFor: rule.KeepFiringFor.String(),
+			Labels:        nonNilMap(rule.Labels),
+			Annotations:   nonNilMap(rule.Annotations),
+		},
+	}
+}
+
+func ruleName(rule rulefmt.Rule) string {
+	if rule.Alert != "" {
+		return rule.Alert
+	}
+	return rule.Record
+}
+
+func ruleType(rule rulefmt.Rule) string {
+	if rule.Alert != "" {
+		return "alert"
+	}
+	return "recording"
+}
+
+func nonNilMap(m map[string]string) map[string]string {
+	if m == nil {
+		return map[string]string{}
+	}
+	return m
+}
+
+func nonNilSlice(s []string) []string {
+	if s == nil {
+		return []string{}
+	}
+	return s
+}
+
+// policyResult is a single item of the JSON list returned by the external policies, it can be also a plain string which is treated as an error message.
+type policyResult struct {
+	Message  string `json:"message"`
+	Severity string `json:"severity"`
+}
+
+func (r *policyResult) UnmarshalJSON(data []byte) error {
+	if err := json.Unmarshal(data, &r.Message); err == nil {
+		return nil
+	}
+	type plain policyResult
+	return json.Unmarshal(data, (*plain)(r))
+}
+
+// policyOutputErrors parses the JSON list of messages returned by the external policy, messages with the warning severity are only logged.
+func policyOutputErrors(output []byte, logFields log.Fields) ([]error, error) {
+	var results []policyResult
+	if output = bytes.TrimSpace(output); len(output) > 0 {
+		if err := json.Unmarshal(output, &results); err != nil {
+			return nil, fmt.Errorf("expected JSON list of messages: %w", err)
+		}
+	}
+	var errs []error
+	for _, result := range results {
+		switch result.Severity {
+		case "", policySeverityError:
+			errs = append(errs, errors.New(result.Message))
+		case policySeverityWarning:
+			log.WithFields(logFields).Warn(result.Message)
+		default:
+			errs = append(errs, fmt.Errorf("message with invalid severity `%s`: %s", result.Severity, result.Message))
+		}
+	}
+	return errs, nil
+}
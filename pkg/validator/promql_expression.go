@@ -18,7 +18,7 @@ import (
 	log "github.com/sirupsen/logrus"
 )
 
-func newExpressionIsValidPromQL(unmarshal unmarshalParamsFunc) (Validator, error) {
+func newExpressionIsValidPromQL(unmarshal UnmarshalParamsFunc) (Validator, error) {
 	params := struct{}{}
 	if err := unmarshal(&params); err != nil {
 		return nil, err
@@ -39,7 +39,7 @@ func (h expressionIsValidPromQL) Validate(_ unmarshaler.RuleGroup, rule rulefmt.
 	return []error{}
 }
 
-func newExpressionDoesNotUseOlderDataThan(unmarshal unmarshalParamsFunc) (Validator, error) {
+func newExpressionDoesNotUseOlderDataThan(unmarshal UnmarshalParamsFunc) (Validator, error) {
 	params := struct {
 		Limit model.Duration `yaml:"limit"`
 	}{}
@@ -90,7 +90,7 @@ func (h expressionDoesNotUseOlderDataThan) Validate(_ unmarshaler.RuleGroup, rul
 	return errs
 }
 
-func newExpressionDoesNotUseLabels(unmarshal unmarshalParamsFunc) (Validator, error) {
+func newExpressionDoesNotUseLabels(unmarshal UnmarshalParamsFunc) (Validator, error) {
 	params := struct {
 		Labels []string `yaml:"labels"`
 	}{}
@@ -132,7 +132,7 @@ type expressionUsesOnlyAllowedLabelsForMetricRegexp struct {
 	metricNameRegexp *regexp.Regexp
 }
 
-func newExpressionUsesOnlyAllowedLabelsForMetricRegexp(unmarshal unmarshalParamsFunc) (Validator, error) {
+func newExpressionUsesOnlyAllowedLabelsForMetricRegexp(unmarshal UnmarshalParamsFunc) (Validator, error) {
 	params := struct {
 		AllowedLabels    []string              `yaml:"allowedLabels"`
 		MetricNameRegexp RegexpWildcardDefault `yaml:"metricNameRegexp"`
@@ -172,7 +172,7 @@ type expressionUsesOnlyAllowedLabelValuesForMetricRegexp struct {
 	metricNameRegexp   *regexp.Regexp
 }
 
-func newExpressionUsesOnlyAllowedLabelValuesForMetricRegexp(unmarshal unmarshalParamsFunc) (Validator, error) {
+func newExpressionUsesOnlyAllowedLabelValuesForMetricRegexp(unmarshal UnmarshalParamsFunc) (Validator, error) {
 	params := struct {
 		AllowedLabelValues map[string][]string   `yaml:"allowedLabelValues"`
 		MetricNameRegexp   RegexpWildcardDefault `yaml:"metricNameRegexp"`
@@ -231,7 +231,7 @@ type expressionDoesNotUseLabelsForMetricRegexp struct {
 	metricNameRegexp *regexp.Regexp
 }
 
-func newExpressionDoesNotUseLabelsForMetricRegexp(unmarshal unmarshalParamsFunc) (Validator, error) {
+func newExpressionDoesNotUseLabelsForMetricRegexp(unmarshal UnmarshalParamsFunc) (Validator, error) {
 	params := struct {
 		Labels           []string              `yaml:"labels"`
 		MetricNameRegexp RegexpWildcardDefault `yaml:"metricNameRegexp"`
@@ -267,7 +267,7 @@ func (h expressionDoesNotUseLabelsForMetricRegexp) Validate(_ unmarshaler.RuleGr
 	return errs
 }
 
-func newExpressionDoesNotUseRangeShorterThan(unmarshal unmarshalParamsFunc) (Validator, error) {
+func newExpressionDoesNotUseRangeShorterThan(unmarshal UnmarshalParamsFunc) (Validator, error) {
 	params := struct {
 		Limit model.Duration `yaml:"limit"`
 	}{}
@@ -310,7 +310,7 @@ func (h expressionDoesNotUseRangeShorterThan) Validate(_ unmarshaler.RuleGroup,
 	return errs
 }
 
-func newExpressionDoesNotUseIrate(unmarshal unmarshalParamsFunc) (Validator, error) {
+func newExpressionDoesNotUseIrate(unmarshal UnmarshalParamsFunc) (Validator, error) {
 	params := struct{}{}
 	if err := unmarshal(&params); err != nil {
 		return nil, err
@@ -341,7 +341,7 @@ func (h expressionDoesNotUseIrate) Validate(_ unmarshaler.RuleGroup, rule rulefm
 	return errs
 }
 
-func newValidFunctionsOnCounters(unmarshal unmarshalParamsFunc) (Validator, error) {
+func newValidFunctionsOnCounters(unmarshal UnmarshalParamsFunc) (Validator, error) {
 	params := struct {
 		AllowHistograms bool `yaml:"allowHistograms"`
 	}{}
@@ -396,7 +396,7 @@ func (h validFunctionsOnCounters) Validate(_ unmarshaler.RuleGroup, rule rulefmt
 	return errs
 }
 
-func newRateBeforeAggregation(unmarshal unmarshalParamsFunc) (Validator, error) {
+func newRateBeforeAggregation(unmarshal UnmarshalParamsFunc) (Validator, error) {
 	params := struct{}{}
 	if err := unmarshal(&params); err != nil {
 		return nil, err
@@ -436,7 +436,7 @@ func (h rateBeforeAggregation) Validate(_ unmarshaler.RuleGroup, rule rulefmt.Ru
 	return errs
 }
 
-func newExpressionCanBeEvaluated(unmarshal unmarshalParamsFunc) (Validator, error) {
+func newExpressionCanBeEvaluated(unmarshal UnmarshalParamsFunc) (Validator, error) {
 	params := struct {
 		TimeSeriesLimit         int           `yaml:"timeSeriesLimit"`
 		EvaluationDurationLimit time.Duration `yaml:"evaluationDurationLimit"`
@@ -472,20 +472,26 @@ func (h expressionCanBeEvaluated) Validate(group unmarshaler.RuleGroup, rule rul
 		log.Error("missing the `prometheus` section of configuration for querying prometheus, skipping check that requires it...")
 		return nil
 	}
-	count, duration, err := prometheusClient.QueryStats(rule.Expr, group.SourceTenants)
+	stats, err := prometheusClient.QueryStatistics(rule.Expr, group.SourceTenants)
 	if err != nil {
 		return append(errs, err)
 	}
-	if h.timeSeriesLimit != 0 && count > h.timeSeriesLimit {
-		errs = append(errs, fmt.Errorf("query returned %d series exceeding the limit %d", count, h.timeSeriesLimit))
+	if h.timeSeriesLimit != 0 && stats.Series > h.timeSeriesLimit {
+		errs = append(errs, fmt.Errorf("query returned %d series exceeding the limit %d", stats.Series, h.timeSeriesLimit))
 	}
-	if h.evaluationDurationLimit != 0 && duration > h.evaluationDurationLimit {
-		errs = append(errs, fmt.Errorf("query took %s which exceeds the configured maximum %s", duration, h.evaluationDurationLimit))
+	if h.evaluationDurationLimit != 0 && stats.Duration > h.evaluationDurationLimit {
+		errs = append(errs, fmt.Errorf("query took %s which exceeds the configured maximum %s", stats.Duration, h.evaluationDurationLimit))
 	}
 	return errs
 }
 
-func newExpressionUsesExistingLabels(unmarshal unmarshalParamsFunc) (Validator, error) {
+func (h expressionCanBeEvaluated) WarmCache(group unmarshaler.RuleGroup, rule rulefmt.Rule, prometheusClient *prometheus.Client) error {
+	// Failed queries are cached as well, so their error does not mean the warming failed.
+	_, _ = prometheusClient.QueryStatistics(rule.Expr, group.SourceTenants)
+	return nil
+}
+
+func newExpressionUsesExistingLabels(unmarshal UnmarshalParamsFunc) (Validator, error) {
 	params := struct{}{}
 	if err := unmarshal(&params); err != nil {
 		return nil, err
@@ -528,7 +534,12 @@ func (h expressionUsesExistingLabels) Validate(group unmarshaler.RuleGroup, rule
 	return errs
 }
 
-func newExpressionSelectorsMatchesAnything(unmarshal unmarshalParamsFunc) (Validator, error) {
+func (h expressionUsesExistingLabels) WarmCache(group unmarshaler.RuleGroup, _ rulefmt.Rule, prometheusClient *prometheus.Client) error {
+	_, err := prometheusClient.Labels(group.SourceTenants)
+	return err
+}
+
+func newExpressionSelectorsMatchesAnything(unmarshal UnmarshalParamsFunc) (Validator, error) {
 	params := struct {
 		MaximumMatchingSeries int `yaml:"maximumMatchingSeries"`
 	}{}
@@ -574,7 +585,89 @@ func (h expressionSelectorsMatchesAnything) Validate(group unmarshaler.RuleGroup
 	return errs
 }
 
-func newExpressionWithNoMetricName(unmarshal unmarshalParamsFunc) (Validator, error) {
+func (h expressionSelectorsMatchesAnything) WarmCache(group unmarshaler.RuleGroup, rule rulefmt.Rule, prometheusClient *prometheus.Client) error {
+	selectors, err := getExpressionSelectors(rule.Expr)
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for _, s := range selectors {
+		if _, err := prometheusClient.SelectorMatchingSeries(s, group.SourceTenants); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func newExpressionDoesNotSelectHighCardinalityMetrics(unmarshal UnmarshalParamsFunc) (Validator, error) {
+	params := struct {
+		SeriesLimit     int      `yaml:"seriesLimit"`
+		NarrowingLabels []string `yaml:"narrowingLabels"`
+	}{}
+	if err := unmarshal(&params); err != nil {
+		return nil, err
+	}
+	if params.SeriesLimit <= 0 {
+		return nil, fmt.Errorf("`seriesLimit` must be greater than 0")
+	}
+	return &expressionDoesNotSelectHighCardinalityMetrics{
+		seriesLimit:     params.SeriesLimit,
+		narrowingLabels: params.NarrowingLabels,
+	}, nil
+}
+
+type expressionDoesNotSelectHighCardinalityMetrics struct {
+	seriesLimit     int
+	narrowingLabels []string
+}
+
+func (h expressionDoesNotSelectHighCardinalityMetrics) String() string {
+	msg := fmt.Sprintf("selectors of metrics with more than %d series in Prometheus use a narrowing label matcher", h.seriesLimit)
+	if len(h.narrowingLabels) > 0 {
+		msg += fmt.Sprintf(" on any of the labels: `%s`", strings.Join(h.narrowingLabels, "`,`"))
+	}
+	return msg
+}
+
+// isNarrowing returns true if the matcher selects only series with a specific value of the label, so it can reduce number of the selected series.
+func (h expressionDoesNotSelectHighCardinalityMetrics) isNarrowing(matcher *labels.Matcher) bool {
+	if matcher.Name == metricNameLabel || len(h.narrowingLabels) > 0 && !slices.Contains(h.narrowingLabels, matcher.Name) {
+		return false
+	}
+	return (matcher.Type == labels.MatchEqual || matcher.Type == labels.MatchRegexp) && !matcher.Matches("")
+}
+
+func (h expressionDoesNotSelectHighCardinalityMetrics) Validate(group unmarshaler.RuleGroup, rule rulefmt.Rule, prometheusClient *prometheus.Client) []error {
+	if prometheusClient == nil {
+		log.Error("missing the `prometheus` section of configuration for querying prometheus, skipping check that requires it...")
+		return nil
+	}
+	selectors, err := getExpressionVectorSelectors(rule.Expr)
+	if err != nil {
+		return []error{err}
+	}
+	metricsSeries, err := prometheusClient.MetricsSeries(group.SourceTenants)
+	if err != nil {
+		return []error{err}
+	}
+	var errs []error
+	for _, s := range selectors {
+		metricName := getVectorSelectorMetricName(s)
+		series, ok := metricsSeries[metricName]
+		if !ok || series <= h.seriesLimit || slices.ContainsFunc(s.LabelMatchers, h.isNarrowing) {
+			continue
+		}
+		errs = append(errs, fmt.Errorf("selector `%s` selects the metric `%s` with %d series exceeding the limit %d without any narrowing label matcher", s, metricName, series, h.seriesLimit))
+	}
+	return errs
+}
+
+func (h expressionDoesNotSelectHighCardinalityMetrics) WarmCache(group unmarshaler.RuleGroup, _ rulefmt.Rule, prometheusClient *prometheus.Client) error {
+	_, err := prometheusClient.MetricsSeries(group.SourceTenants)
+	return err
+}
+
+func newExpressionWithNoMetricName(unmarshal UnmarshalParamsFunc) (Validator, error) {
 	params := struct{}{}
 	if err := unmarshal(&params); err != nil {
 		return nil, err
@@ -602,7 +695,7 @@ func (e expressionWithNoMetricName) Validate(_ unmarshaler.RuleGroup, rule rulef
 	return errs
 }
 
-func newExpressionDoesNotUseMetrics(unmarshal unmarshalParamsFunc) (Validator, error) {
+func newExpressionDoesNotUseMetrics(unmarshal UnmarshalParamsFunc) (Validator, error) {
 	params := struct {
 		MetricNameRegexps []RegexpEmptyDefault `yaml:"metricNameRegexps"`
 	}{}
@@ -650,7 +743,7 @@ func (h expressionDoesNotUseMetrics) Validate(_ unmarshaler.RuleGroup, rule rule
 	return errs
 }
 
-func newExpressionIsWellFormatted(unmarshal unmarshalParamsFunc) (Validator, error) {
+func newExpressionIsWellFormatted(unmarshal UnmarshalParamsFunc) (Validator, error) {
 	params := struct {
 		SkipExpressionsWithComments bool `yaml:"skipExpressionsWithComments"`
 		ShowFormatted               bool `yaml:"showExpectedForm"`
@@ -692,7 +785,7 @@ func (h expressionIsWellFormatted) Validate(_ unmarshaler.RuleGroup, rule rulefm
 	return []error{errors.New(errorText)}
 }
 
-func newExpressionDoesNotUseExperimentalFunctions(unmarshal unmarshalParamsFunc) (Validator, error) {
+func newExpressionDoesNotUseExperimentalFunctions(unmarshal UnmarshalParamsFunc) (Validator, error) {
 	params := struct{}{}
 	if err := unmarshal(&params); err != nil {
 		return nil, err
@@ -726,7 +819,7 @@ func (h expressionDoesNotUseExperimentalFunctions) Validate(_ unmarshaler.RuleGr
 	return []error{}
 }
 
-func newExpressionUsesUnderscoresInLargeNumbers(unmarshal unmarshalParamsFunc) (Validator, error) {
+func newExpressionUsesUnderscoresInLargeNumbers(unmarshal UnmarshalParamsFunc) (Validator, error) {
 	params := struct{}{}
 	if err := unmarshal(&params); err != nil {
 		return nil, err
@@ -764,7 +857,7 @@ func (h expressionUsesUnderscoresInLargeNumbers) Validate(_ unmarshaler.RuleGrou
 	return []error{}
 }
 
-func newExpressionDoesNotUseClassicHistogramBucketOperations(unmarshal unmarshalParamsFunc) (Validator, error) {
+func newExpressionDoesNotUseClassicHistogramBucketOperations(unmarshal UnmarshalParamsFunc) (Validator, error) {
 	params := struct{}{}
 	if err := unmarshal(&params); err != nil {
 		return nil, err
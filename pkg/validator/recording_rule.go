@@ -7,9 +7,10 @@ import (
 	"github.com/fusakla/promruval/v3/pkg/prometheus"
 	"github.com/fusakla/promruval/v3/pkg/unmarshaler"
 	"github.com/prometheus/prometheus/model/rulefmt"
+	log "github.com/sirupsen/logrus"
 )
 
-func newRecordedMetricNameMatchesRegexp(unmarshal unmarshalParamsFunc) (Validator, error) {
+func newRecordedMetricNameMatchesRegexp(unmarshal UnmarshalParamsFunc) (Validator, error) {
 	params := struct {
 		Regexp   RegexpForbidEmpty `yaml:"regexp"`
 		Negative bool              `yaml:"negative"`
@@ -40,7 +41,7 @@ func (h recordedMetricNameMatchesRegexp) Validate(_ unmarshaler.RuleGroup, rule
 	return errs
 }
 
-func newRecordedMetricNameDoesNotMatchRegexp(unmarshal unmarshalParamsFunc) (Validator, error) {
+func newRecordedMetricNameDoesNotMatchRegexp(unmarshal UnmarshalParamsFunc) (Validator, error) {
 	params := struct {
 		Regexp RegexpForbidEmpty `yaml:"regexp"`
 	}{}
@@ -52,3 +53,44 @@ func newRecordedMetricNameDoesNotMatchRegexp(unmarshal unmarshalParamsFunc) (Val
 		negative: true,
 	}, nil
 }
+
+func newRecordingRuleOutputCardinalityBelow(unmarshal UnmarshalParamsFunc) (Validator, error) {
+	params := struct {
+		Limit int `yaml:"limit"`
+	}{}
+	if err := unmarshal(&params); err != nil {
+		return nil, err
+	}
+	if params.Limit <= 0 {
+		return nil, fmt.Errorf("`limit` must be greater than 0")
+	}
+	return &recordingRuleOutputCardinalityBelow{limit: params.Limit}, nil
+}
+
+type recordingRuleOutputCardinalityBelow struct {
+	limit int
+}
+
+func (h recordingRuleOutputCardinalityBelow) String() string {
+	return fmt.Sprintf("produces less than %d series in the live Prometheus instance", h.limit)
+}
+
+func (h recordingRuleOutputCardinalityBelow) Validate(group unmarshaler.RuleGroup, rule rulefmt.Rule, prometheusClient *prometheus.Client) []error {
+	if prometheusClient == nil {
+		log.Error("missing the `prometheus` section of configuration for querying prometheus, skipping check that requires it...")
+		return nil
+	}
+	cardinality, err := prometheusClient.ExpressionCardinality(rule.Expr, group.SourceTenants)
+	if err != nil {
+		return []error{err}
+	}
+	if cardinality >= h.limit {
+		return []error{fmt.Errorf("recording rule produces %d series which is not below the limit %d", cardinality, h.limit)}
+	}
+	return nil
+}
+
+func (h recordingRuleOutputCardinalityBelow) WarmCache(group unmarshaler.RuleGroup, rule rulefmt.Rule, prometheusClient *prometheus.Client) error {
+	_, err := prometheusClient.ExpressionCardinality(rule.Expr, group.SourceTenants)
+	return err
+}
@@ -0,0 +1,103 @@
+package validator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/fusakla/promruval/v3/pkg/prometheus"
+	"github.com/fusakla/promruval/v3/pkg/unmarshaler"
+	"github.com/open-policy-agent/opa/v1/rego"
+	"github.com/prometheus/prometheus/model/rulefmt"
+)
+
+const defaultRegoQuery = "data.promruval.deny"
+
+type regoPolicyParams struct {
+	Files []string `yaml:"files"`
+	Query string   `yaml:"query"`
+
+	resolvedFiles []string
+}
+
+func (p *regoPolicyParams) ResolveRelativePaths(baseDir string) error {
+	p.resolvedFiles = make([]string, len(p.Files))
+	for i, f := range p.Files {
+		if filepath.IsAbs(f) {
+			return fmt.Errorf("rego policy file path must be relative to the config file, got %s", f)
+		}
+		p.resolvedFiles[i] = filepath.Join(baseDir, f)
+	}
+	return nil
+}
+
+func newRegoPolicy(unmarshal UnmarshalParamsFunc) (Validator, error) {
+	params := regoPolicyParams{}
+	if err := unmarshal(&params); err != nil {
+		return nil, err
+	}
+	if len(params.Files) == 0 {
+		return nil, fmt.Errorf("at least one rego policy file needs to be set in `files`")
+	}
+	if params.Query == "" {
+		params.Query = defaultRegoQuery
+	}
+	if params.resolvedFiles == nil {
+		if err := params.ResolveRelativePaths(""); err != nil {
+			return nil, err
+		}
+	}
+	query, err := rego.New(rego.Query(params.Query), rego.Load(params.resolvedFiles, nil)).PrepareForEval(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("failed to load rego policy from %s: %w", strings.Join(params.Files, ", "), err)
+	}
+	return &regoPolicy{files: params.Files, query: params.Query, preparedQuery: query}, nil
+}
+
+type regoPolicy struct {
+	files         []string
+	query         string
+	preparedQuery rego.PreparedEvalQuery
+}
+
+func (h regoPolicy) String() string {
+	return fmt.Sprintf("does not violate the Rego policy `%s` defined in `%s`", h.query, strings.Join(h.files, "`, `"))
+}
+
+func (h regoPolicy) Validate(group unmarshaler.RuleGroup, rule rulefmt.Rule, _ *prometheus.Client) []error {
+	results, err := h.preparedQuery.Eval(context.Background(), rego.EvalInput(newPolicyInput(group, rule)))
+	if err != nil {
+		return []error{fmt.Errorf("failed to evaluate the Rego policy `%s`: %w", h.query, err)}
+	}
+	var errs []error
+	for _, result := range results {
+		for _, expression := range result.Expressions {
+			errs = append(errs, regoDenyMessages(h.query, expression.Value)...)
+		}
+	}
+	return errs
+}
+
+// regoDenyMessages converts the query result to errors, the result is expected to be a collection of strings or objects with the `msg` field.
+func regoDenyMessages(query string, value any) []error {
+	var errs []error
+	switch v := value.(type) {
+	case []any:
+		for _, item := range v {
+			errs = append(errs, regoDenyMessages(query, item)...)
+		}
+	case string:
+		errs = append(errs, errors.New(v))
+	case map[string]any:
+		msg, ok := v["msg"].(string)
+		if !ok {
+			return []error{fmt.Errorf("the Rego policy `%s` returned an object without the `msg` string field: %v", query, v)}
+		}
+		errs = append(errs, errors.New(msg))
+	default:
+		return []error{fmt.Errorf("the Rego policy `%s` returned unexpected value %v, expected a set of strings or objects with the `msg` field", query, v)}
+	}
+	return errs
+}
@@ -1,6 +1,7 @@
 package validator
 
 import (
+	"errors"
 	"fmt"
 	"regexp"
 
@@ -14,6 +15,50 @@ type Validator interface {
 	Validate(group unmarshaler.RuleGroup, rule rulefmt.Rule, prometheusClient *prometheus.Client) []error
 }
 
+// CacheWarmer is implemented by the validators querying the Prometheus, so its query cache can be filled without validating the rules.
+type CacheWarmer interface {
+	WarmCache(group unmarshaler.RuleGroup, rule rulefmt.Rule, prometheusClient *prometheus.Client) error
+}
+
+// Closer is implemented by the validators holding resources, which need to be released once the validator is not used anymore.
+type Closer interface {
+	Close() error
+}
+
+// Close releases the resources held by the validator, operands of the logical validators are closed as well.
+func Close(v Validator) error {
+	switch v := v.(type) {
+	case *Logical:
+		var errs []error
+		for _, operand := range v.Operands {
+			errs = append(errs, Close(operand.Validator))
+		}
+		return errors.Join(errs...)
+	case Closer:
+		return v.Close()
+	}
+	return nil
+}
+
+// WarmCache sends the Prometheus queries the validator needs to validate the rule, operands of the logical validators are warmed as well.
+// Validators not querying the Prometheus do nothing.
+func WarmCache(v Validator, group unmarshaler.RuleGroup, rule rulefmt.Rule, prometheusClient *prometheus.Client) error {
+	if prometheusClient == nil {
+		return nil
+	}
+	switch v := v.(type) {
+	case *Logical:
+		var errs []error
+		for _, operand := range v.Operands {
+			errs = append(errs, WarmCache(operand.Validator, group, rule, prometheusClient))
+		}
+		return errors.Join(errs...)
+	case CacheWarmer:
+		return v.WarmCache(group, rule, prometheusClient)
+	}
+	return nil
+}
+
 const (
 	matchAnythingRegexp = ".*"
 )
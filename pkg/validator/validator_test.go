@@ -1,16 +1,24 @@
 package validator
 
 import (
+	"context"
 	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
 	"reflect"
 	"regexp"
+	"runtime"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/fusakla/promruval/v3/pkg/config"
 	"github.com/fusakla/promruval/v3/pkg/prometheus"
 	"github.com/fusakla/promruval/v3/pkg/unmarshaler"
 	"github.com/prometheus/common/model"
 	"github.com/prometheus/prometheus/model/rulefmt"
+	"gopkg.in/yaml.v3"
 	"gotest.tools/assert"
 )
 
@@ -22,6 +30,16 @@ func mustCompileAnchoredRegexp(regexpString string) *regexp.Regexp {
 	return compiled
 }
 
+func mustNewCelExpression(expression, message string) Validator {
+	v, err := newCelExpression(func(v interface{}) error {
+		return yaml.Unmarshal(fmt.Appendf(nil, "expression: %q\nmessage: %q", expression, message), v)
+	})
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
+
 var testCases = []struct {
 	name           string
 	validator      Validator
@@ -214,6 +232,22 @@ var testCases = []struct {
 	{name: "noMatches", validator: expressionSelectorsMatchesAnything{}, promClient: prometheus.NewClientMock(prometheus.NewSeriesResponseMock(0), 0, false, false), rule: rulefmt.Rule{Expr: `up{foo="bar"}`}, expectedErrors: 1},
 	{name: "queryError", validator: expressionSelectorsMatchesAnything{}, promClient: prometheus.NewClientMock(prometheus.NewSeriesResponseMock(2), 0, false, true), rule: rulefmt.Rule{Expr: `up{foo="bar"}`}, expectedErrors: 1},
 
+	// expressionDoesNotSelectHighCardinalityMetrics
+	{name: "lowCardinalityMetric", validator: expressionDoesNotSelectHighCardinalityMetrics{seriesLimit: 100}, promClient: prometheus.NewClientMock(prometheus.NewTSDBStatusResponseMock(map[string]int{"up": 10}), 0, false, false), rule: rulefmt.Rule{Expr: `sum(up)`}, expectedErrors: 0},
+	{name: "unknownMetric", validator: expressionDoesNotSelectHighCardinalityMetrics{seriesLimit: 100}, promClient: prometheus.NewClientMock(prometheus.NewTSDBStatusResponseMock(map[string]int{"up": 1000}), 0, false, false), rule: rulefmt.Rule{Expr: `sum(foo)`}, expectedErrors: 0},
+	{name: "highCardinalityMetric", validator: expressionDoesNotSelectHighCardinalityMetrics{seriesLimit: 100}, promClient: prometheus.NewClientMock(prometheus.NewTSDBStatusResponseMock(map[string]int{"up": 1000}), 0, false, false), rule: rulefmt.Rule{Expr: `sum(up) / sum(up{job!="foo"})`}, expectedErrors: 2},
+	{name: "highCardinalityMetricNarrowed", validator: expressionDoesNotSelectHighCardinalityMetrics{seriesLimit: 100}, promClient: prometheus.NewClientMock(prometheus.NewTSDBStatusResponseMock(map[string]int{"up": 1000}), 0, false, false), rule: rulefmt.Rule{Expr: `sum(up{job="foo"}) / sum({__name__="up", job=~"foo|bar"})`}, expectedErrors: 0},
+	{name: "highCardinalityMetricMatchingEmpty", validator: expressionDoesNotSelectHighCardinalityMetrics{seriesLimit: 100}, promClient: prometheus.NewClientMock(prometheus.NewTSDBStatusResponseMock(map[string]int{"up": 1000}), 0, false, false), rule: rulefmt.Rule{Expr: `sum(up{job=~".*"})`}, expectedErrors: 1},
+	{name: "highCardinalityMetricNarrowedByOtherLabel", validator: expressionDoesNotSelectHighCardinalityMetrics{seriesLimit: 100, narrowingLabels: []string{"namespace"}}, promClient: prometheus.NewClientMock(prometheus.NewTSDBStatusResponseMock(map[string]int{"up": 1000}), 0, false, false), rule: rulefmt.Rule{Expr: `sum(up{job="foo"})`}, expectedErrors: 1},
+	{name: "highCardinalityMetricNarrowedByAllowedLabel", validator: expressionDoesNotSelectHighCardinalityMetrics{seriesLimit: 100, narrowingLabels: []string{"namespace"}}, promClient: prometheus.NewClientMock(prometheus.NewTSDBStatusResponseMock(map[string]int{"up": 1000}), 0, false, false), rule: rulefmt.Rule{Expr: `sum(up{namespace="foo"})`}, expectedErrors: 0},
+	{name: "tsdbStatusError", validator: expressionDoesNotSelectHighCardinalityMetrics{seriesLimit: 100}, promClient: prometheus.NewClientMock(prometheus.NewTSDBStatusResponseMock(map[string]int{}), 0, false, true), rule: rulefmt.Rule{Expr: `up`}, expectedErrors: 1},
+
+	// recordingRuleOutputCardinalityBelow
+	{name: "outputCardinalityBelow", validator: recordingRuleOutputCardinalityBelow{limit: 10}, promClient: prometheus.NewClientMock(prometheus.NewQueryValueResponseMock(9), 0, false, false), rule: rulefmt.Rule{Record: "foo", Expr: "sum(up) by (job)"}, expectedErrors: 0},
+	{name: "outputCardinalityNotBelow", validator: recordingRuleOutputCardinalityBelow{limit: 10}, promClient: prometheus.NewClientMock(prometheus.NewQueryValueResponseMock(10), 0, false, false), rule: rulefmt.Rule{Record: "foo", Expr: "sum(up) by (job)"}, expectedErrors: 1},
+	{name: "outputCardinalityEmpty", validator: recordingRuleOutputCardinalityBelow{limit: 10}, promClient: prometheus.NewClientMock(prometheus.NewQueryVectorResponseMock(0), 0, false, false), rule: rulefmt.Rule{Record: "foo", Expr: "sum(up) by (job)"}, expectedErrors: 0},
+	{name: "outputCardinalityError", validator: recordingRuleOutputCardinalityBelow{limit: 10}, promClient: prometheus.NewClientMock(prometheus.NewQueryValueResponseMock(1), 0, false, true), rule: rulefmt.Rule{Record: "foo", Expr: "sum(up) by (job)"}, expectedErrors: 1},
+
 	// expressionUsesExistingLabels
 	{name: "labelsExists", validator: expressionUsesExistingLabels{}, promClient: prometheus.NewClientMock([]string{"__name__", "foo"}, 0, false, false), rule: rulefmt.Rule{Expr: `up{foo="bar"}`}, expectedErrors: 0},
 	{name: "labelsDoesNotExist", validator: expressionUsesExistingLabels{}, promClient: prometheus.NewClientMock([]string{"__name__"}, 0, false, false), rule: rulefmt.Rule{Expr: `up{foo="bar"}`}, expectedErrors: 1},
@@ -290,6 +324,22 @@ var testCases = []struct {
 	{name: "validWithCommentThatShouldBeIgnored", validator: expressionIsWellFormatted{showFormatted: true}, rule: rulefmt.Rule{Expr: `up == 1 # fooo`}, expectedErrors: 0},
 	{name: "invalidButWithCommentAndShouldBeSkipped", validator: expressionIsWellFormatted{showFormatted: true, skipExpressionsWithComments: true}, rule: rulefmt.Rule{Expr: `up           == 1 # fooo`}, expectedErrors: 0},
 
+	// groupEvaluationCostWithinBudget
+	{name: "evaluationCostWithinBudget", validator: groupEvaluationCostWithinBudget{maxIntervalFraction: 0.5, maxSamples: 1000, defaultInterval: time.Minute}, promClient: prometheus.NewClientMock(prometheus.NewQueryStatsResponseMock(1, 10*time.Second, 400), 0, false, false), group: unmarshaler.RuleGroup{Rules: []unmarshaler.RuleWithComment{unmarshaler.NewRuleWithComment(rulefmt.Rule{Record: "foo", Expr: "up"}), unmarshaler.NewRuleWithComment(rulefmt.Rule{Record: "bar", Expr: "up"})}}, expectedErrors: 0},
+	{name: "evaluationCostExceedsInterval", validator: groupEvaluationCostWithinBudget{maxIntervalFraction: 0.5, defaultInterval: time.Minute}, promClient: prometheus.NewClientMock(prometheus.NewQueryStatsResponseMock(1, 20*time.Second, 400), 0, false, false), group: unmarshaler.RuleGroup{Rules: []unmarshaler.RuleWithComment{unmarshaler.NewRuleWithComment(rulefmt.Rule{Record: "foo", Expr: "up"}), unmarshaler.NewRuleWithComment(rulefmt.Rule{Record: "bar", Expr: "up"})}}, expectedErrors: 1},
+	{name: "evaluationCostWithinGroupInterval", validator: groupEvaluationCostWithinBudget{maxIntervalFraction: 0.5, defaultInterval: time.Minute}, promClient: prometheus.NewClientMock(prometheus.NewQueryStatsResponseMock(1, 20*time.Second, 400), 0, false, false), group: unmarshaler.RuleGroup{Interval: model.Duration(2 * time.Minute), Rules: []unmarshaler.RuleWithComment{unmarshaler.NewRuleWithComment(rulefmt.Rule{Record: "foo", Expr: "up"}), unmarshaler.NewRuleWithComment(rulefmt.Rule{Record: "bar", Expr: "up"})}}, expectedErrors: 0},
+	{name: "evaluationCostExceedsSamples", validator: groupEvaluationCostWithinBudget{maxIntervalFraction: 0.5, maxSamples: 500, defaultInterval: time.Minute}, promClient: prometheus.NewClientMock(prometheus.NewQueryStatsResponseMock(1, time.Second, 400), 0, false, false), group: unmarshaler.RuleGroup{Rules: []unmarshaler.RuleWithComment{unmarshaler.NewRuleWithComment(rulefmt.Rule{Record: "foo", Expr: "up"}), unmarshaler.NewRuleWithComment(rulefmt.Rule{Record: "bar", Expr: "up"})}}, expectedErrors: 1},
+	{name: "evaluationCostQueryError", validator: groupEvaluationCostWithinBudget{maxIntervalFraction: 0.5, defaultInterval: time.Minute}, promClient: prometheus.NewClientMock(prometheus.NewQueryStatsResponseMock(1, time.Second, 400), 0, false, true), group: unmarshaler.RuleGroup{Rules: []unmarshaler.RuleWithComment{unmarshaler.NewRuleWithComment(rulefmt.Rule{Record: "foo", Expr: "up"})}}, expectedErrors: 1},
+
+	// alertFiringHistory
+	{name: "firingHistoryReport", validator: alertFiringHistory{window: time.Hour, step: time.Minute}, promClient: prometheus.NewClientMock(prometheus.NewQueryMatrixResponseMock(activityMatrix(time.Minute, "10101")), 0, false, false), rule: rulefmt.Rule{Alert: "foo", Expr: "up == 0"}, expectedErrors: 0},
+	{name: "firingHistoryReportNoFiring", validator: alertFiringHistory{window: time.Hour, step: time.Minute}, promClient: prometheus.NewClientMock(prometheus.NewQueryMatrixResponseMock(model.Matrix{}), 0, false, false), rule: rulefmt.Rule{Alert: "foo", Expr: "up == 0"}, expectedErrors: 0},
+	{name: "firingHistoryWithinLimit", validator: alertFiringHistory{window: time.Hour, step: time.Minute, maxFirings: 3}, promClient: prometheus.NewClientMock(prometheus.NewQueryMatrixResponseMock(activityMatrix(time.Minute, "10101")), 0, false, false), rule: rulefmt.Rule{Alert: "foo", Expr: "up == 0"}, expectedErrors: 0},
+	{name: "firingHistoryTooManyFirings", validator: alertFiringHistory{window: time.Hour, step: time.Minute, maxFirings: 2}, promClient: prometheus.NewClientMock(prometheus.NewQueryMatrixResponseMock(activityMatrix(time.Minute, "10101")), 0, false, false), rule: rulefmt.Rule{Alert: "foo", Expr: "up == 0"}, expectedErrors: 1},
+	{name: "firingHistoryForPreventsFirings", validator: alertFiringHistory{window: time.Hour, step: time.Minute, maxFirings: 2}, promClient: prometheus.NewClientMock(prometheus.NewQueryMatrixResponseMock(activityMatrix(time.Minute, "10101")), 0, false, false), rule: rulefmt.Rule{Alert: "foo", Expr: "up == 0", For: model.Duration(time.Minute)}, expectedErrors: 0},
+	{name: "firingHistoryTooLong", validator: alertFiringHistory{window: time.Hour, step: time.Minute, maxFiringDuration: 2 * time.Minute}, promClient: prometheus.NewClientMock(prometheus.NewQueryMatrixResponseMock(activityMatrix(time.Minute, "111")), 0, false, false), rule: rulefmt.Rule{Alert: "foo", Expr: "up == 0"}, expectedErrors: 1},
+	{name: "firingHistoryQueryError", validator: alertFiringHistory{window: time.Hour, step: time.Minute, maxFirings: 2}, promClient: prometheus.NewClientMock(prometheus.NewQueryMatrixResponseMock(model.Matrix{}), 0, false, true), rule: rulefmt.Rule{Alert: "foo", Expr: "up == 0"}, expectedErrors: 1},
+
 	// maxRulesPerGroup
 	{name: "allowedNumberOfGroups", validator: maxRulesPerGroup{limit: 2}, group: unmarshaler.RuleGroup{Rules: []unmarshaler.RuleWithComment{{}, {}}}, expectedErrors: 0},
 	{name: "tooManyRules", validator: maxRulesPerGroup{limit: 1}, group: unmarshaler.RuleGroup{Rules: []unmarshaler.RuleWithComment{{}, {}}}, expectedErrors: 1},
@@ -369,6 +419,181 @@ var testCases = []struct {
 	{name: "doesNotContainTypos_typos_in_annotation_levenshtein_dst", validator: doesNotContainTypos{maxLevenshteinDistance: 1, wellKnownAnnotations: []string{"title"}}, rule: rulefmt.Rule{Annotations: map[string]string{"tittle": "xxx"}}, expectedErrors: 1},
 	{name: "doesNotContainTypos_typos_in_expr_ratio_dst_high_ratio", validator: doesNotContainTypos{maxDifferenceRatio: 0.1, wellKnownSeriesLabels: []string{"pod"}}, rule: rulefmt.Rule{Expr: `kube_pod_info{pot="foo"}`}, expectedErrors: 0},
 	{name: "doesNotContainTypos_typos_in_expr_ratio_dst_low_ratio", validator: doesNotContainTypos{maxDifferenceRatio: 0.1, wellKnownSeriesLabels: []string{"pooooooood"}}, rule: rulefmt.Rule{Expr: `kube_pod_info{poooooooot="foo"}`}, expectedErrors: 1},
+
+	// celExpression
+	{name: "celExpression_valid", validator: mustNewCelExpression(`rule.labels.severity != "critical" || "runbook_url" in rule.annotations`, ""), rule: rulefmt.Rule{Alert: "Foo", Labels: map[string]string{"severity": "critical"}, Annotations: map[string]string{"runbook_url": "http://foo.bar"}}, expectedErrors: 0},
+	{name: "celExpression_invalid", validator: mustNewCelExpression(`rule.labels.severity != "critical" || "runbook_url" in rule.annotations`, ""), rule: rulefmt.Rule{Alert: "Foo", Labels: map[string]string{"severity": "critical"}}, expectedErrors: 1},
+	{name: "celExpression_missingKey", validator: mustNewCelExpression(`rule.labels.severity == "critical"`, ""), rule: rulefmt.Rule{Alert: "Foo"}, expectedErrors: 1},
+	{name: "celExpression_optionalKey", validator: mustNewCelExpression(`rule.labels.?severity.orValue("") != "critical"`, ""), rule: rulefmt.Rule{Alert: "Foo"}, expectedErrors: 0},
+	{name: "celExpression_duration", validator: mustNewCelExpression(`rule.type != "alert" || rule["for"] <= duration("1h")`, ""), rule: rulefmt.Rule{Alert: "Foo", For: model.Duration(time.Hour * 2)}, expectedErrors: 1},
+	{name: "celExpression_group", validator: mustNewCelExpression(`group.interval >= duration("1m") && size(group.source_tenants) == 0`, ""), group: unmarshaler.RuleGroup{Interval: model.Duration(time.Minute)}, rule: rulefmt.Rule{Record: "foo"}, expectedErrors: 0},
+	{name: "celExpression_metrics", validator: mustNewCelExpression(`!metrics(rule.expr).exists(m, m.startsWith("kube_"))`, ""), rule: rulefmt.Rule{Expr: `up * on(pod) kube_pod_info`}, expectedErrors: 1},
+	{name: "celExpression_labels", validator: mustNewCelExpression(`labels(rule.expr).all(l, l in ["pod", "__name__"])`, ""), rule: rulefmt.Rule{Expr: `sum by (pod) (up{pod="foo"})`}, expectedErrors: 0},
+	{name: "celExpression_invalidPromQL", validator: mustNewCelExpression(`size(metrics(rule.expr)) > 0`, ""), rule: rulefmt.Rule{Expr: `up{`}, expectedErrors: 1},
+}
+
+func TestCelExpressionMessage(t *testing.T) {
+	v := mustNewCelExpression(`rule.labels.severity in ["info", "warning", "critical"]`, "alert {{ .rule.name }} has invalid severity `{{ .rule.labels.severity }}`")
+	errs := v.Validate(unmarshaler.RuleGroup{}, rulefmt.Rule{Alert: "Foo", Labels: map[string]string{"severity": "page"}}, nil)
+	assert.Equal(t, len(errs), 1)
+	assert.Equal(t, errs[0].Error(), "alert Foo has invalid severity `page`")
+}
+
+const testRegoPolicy = `package promruval
+
+deny contains msg if {
+	input.rule.type == "alert"
+	input.rule.labels.severity == "critical"
+	not input.rule.annotations.runbook_url
+	msg := sprintf("critical alert %s must have the runbook_url annotation", [input.rule.name])
+}
+
+deny contains {"msg": "group interval must be set"} if {
+	input.group.interval == "0s"
+}
+`
+
+func TestRegoPolicy(t *testing.T) {
+	baseDir := t.TempDir()
+	assert.NilError(t, os.MkdirAll(filepath.Join(baseDir, "policies"), 0o755))
+	assert.NilError(t, os.WriteFile(filepath.Join(baseDir, "policies", "alerts.rego"), []byte(testRegoPolicy), 0o600))
+
+	validatorConfig := config.ValidatorConfig{ValidatorType: "regoPolicy", BaseDir: baseDir}
+	assert.NilError(t, yaml.Unmarshal([]byte(`files: ["policies/alerts.rego"]`), &validatorConfig.Params))
+	v, err := NewFromConfig(config.AlertScope, validatorConfig)
+	assert.NilError(t, err)
+
+	group := unmarshaler.RuleGroup{Interval: model.Duration(time.Minute)}
+	errs := v.Validate(group, rulefmt.Rule{Alert: "Foo", Labels: map[string]string{"severity": "critical"}, Annotations: map[string]string{"runbook_url": "http://foo.bar"}}, nil)
+	assert.Equal(t, len(errs), 0)
+	errs = v.Validate(group, rulefmt.Rule{Alert: "Foo", Labels: map[string]string{"severity": "critical"}}, nil)
+	assert.Equal(t, len(errs), 1)
+	assert.Equal(t, errs[0].Error(), "critical alert Foo must have the runbook_url annotation")
+	errs = v.Validate(unmarshaler.RuleGroup{}, rulefmt.Rule{Alert: "Foo", Labels: map[string]string{"severity": "critical"}}, nil)
+	assert.Equal(t, len(errs), 2)
+
+	validatorConfig.BaseDir = t.TempDir()
+	_, err = NewFromConfig(config.AlertScope, validatorConfig)
+	assert.ErrorContains(t, err, "failed to load rego policy")
+}
+
+func TestExec(t *testing.T) {
+	baseDir := t.TempDir()
+	script := `#!/bin/sh
+input=$(cat)
+case "$input" in
+  *'"severity":"critical"'*) echo '["missing runbook", {"message": "consider adding a dashboard", "severity": "warning"}]' ;;
+  *'"severity":"invalid"'*) echo 'not a json' ;;
+  *'"severity":"slow"'*) sleep 5 ;;
+  *'"severity":"failing"'*) echo "boom" >&2; exit 1 ;;
+  *) echo '[]' ;;
+esac
+`
+	assert.NilError(t, os.WriteFile(filepath.Join(baseDir, "check.sh"), []byte(script), 0o700))
+
+	validatorConfig := config.ValidatorConfig{ValidatorType: "exec", BaseDir: baseDir}
+	assert.NilError(t, yaml.Unmarshal([]byte(`{command: ./check.sh, timeout: 1s, maxConcurrency: 2}`), &validatorConfig.Params))
+	v, err := NewFromConfig(config.AlertScope, validatorConfig)
+	assert.NilError(t, err)
+
+	tests := []struct {
+		severity       string
+		expectedErrors []string
+	}{
+		{severity: "info", expectedErrors: nil},
+		{severity: "critical", expectedErrors: []string{"missing runbook"}},
+		{severity: "invalid", expectedErrors: []string{"invalid output of the command `./check.sh`: expected JSON list of messages"}},
+		{severity: "slow", expectedErrors: []string{"command `./check.sh` timed out after 1s"}},
+		{severity: "failing", expectedErrors: []string{"command `./check.sh` failed: exit status 1, stderr: boom"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.severity, func(t *testing.T) {
+			errs := v.Validate(unmarshaler.RuleGroup{}, rulefmt.Rule{Alert: "Foo", Labels: map[string]string{"severity": tt.severity}}, nil)
+			assert.Equal(t, len(errs), len(tt.expectedErrors), "unexpected errors: %s", errs)
+			for i, err := range errs {
+				assert.ErrorContains(t, err, tt.expectedErrors[i])
+			}
+		})
+	}
+
+	validatorConfig.BaseDir = t.TempDir()
+	_, err = NewFromConfig(config.AlertScope, validatorConfig)
+	assert.ErrorContains(t, err, "invalid command")
+
+	// Config in the current directory and library use without the base dir must not look the command up in PATH.
+	t.Chdir(baseDir)
+	for _, dir := range []string{".", ""} {
+		validatorConfig.BaseDir = dir
+		v, err := NewFromConfig(config.AlertScope, validatorConfig)
+		assert.NilError(t, err)
+		errs := v.Validate(unmarshaler.RuleGroup{}, rulefmt.Rule{Alert: "Foo", Labels: map[string]string{"severity": "critical"}}, nil)
+		assert.Equal(t, len(errs), 1, "unexpected errors: %s", errs)
+	}
+}
+
+func TestWasmPlugin(t *testing.T) {
+	if testing.Short() {
+		t.Skip("building the WASM plugin is slow")
+	}
+	goBinary, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("go binary not found")
+	}
+	baseDir := t.TempDir()
+	build := exec.Command(goBinary, "build", "-buildmode=c-shared", "-o", filepath.Join(baseDir, "plugin.wasm"), "../../examples/wasm-plugin")
+	build.Env = append(os.Environ(), "GOOS=wasip1", "GOARCH=wasm")
+	out, err := build.CombinedOutput()
+	assert.NilError(t, err, string(out))
+
+	validatorConfig := config.ValidatorConfig{ValidatorType: "wasmPlugin", BaseDir: baseDir}
+	assert.NilError(t, yaml.Unmarshal([]byte(`file: plugin.wasm`), &validatorConfig.Params))
+	v, err := NewFromConfig(config.AlertScope, validatorConfig)
+	assert.NilError(t, err)
+
+	errs := v.Validate(unmarshaler.RuleGroup{}, rulefmt.Rule{Alert: "Foo", Labels: map[string]string{"severity": "critical"}, Annotations: map[string]string{"runbook_url": "http://foo.bar"}}, nil)
+	assert.Equal(t, len(errs), 0, "unexpected errors: %s", errs)
+	// Run it concurrently to use multiple instances of the plugin.
+	var wg sync.WaitGroup
+	for range 2 * runtime.NumCPU() {
+		wg.Go(func() {
+			errs := v.Validate(unmarshaler.RuleGroup{}, rulefmt.Rule{Alert: "Foo", Labels: map[string]string{"severity": "critical"}}, nil)
+			assert.Equal(t, len(errs), 1, "unexpected errors: %s", errs)
+			assert.Equal(t, errs[0].Error(), "critical alert Foo must have the runbook_url annotation")
+		})
+	}
+	wg.Wait()
+
+	// Loading the same plugin again, for example on the config reload in the watch mode, reuses the module.
+	reloaded, err := NewFromConfig(config.AlertScope, validatorConfig)
+	assert.NilError(t, err)
+	assert.Equal(t, reloaded.(*wasmPlugin).module, v.(*wasmPlugin).module)
+	// Changed plugin is compiled again, appended is a custom section named `test` with a single byte.
+	wasm, err := os.ReadFile(filepath.Join(baseDir, "plugin.wasm"))
+	assert.NilError(t, err)
+	assert.NilError(t, os.WriteFile(filepath.Join(baseDir, "plugin.wasm"), append(wasm, 0, 6, 4, 't', 'e', 's', 't', 0), 0o600))
+	changed, err := NewFromConfig(config.AlertScope, validatorConfig)
+	assert.NilError(t, err)
+	assert.Assert(t, changed.(*wasmPlugin).module != v.(*wasmPlugin).module)
+	errs = changed.Validate(unmarshaler.RuleGroup{}, rulefmt.Rule{Alert: "Foo", Labels: map[string]string{"severity": "critical"}}, nil)
+	assert.Equal(t, len(errs), 1, "unexpected errors: %s", errs)
+
+	// The previous version of the module is closed only after all the plugins using it are closed.
+	absPath, err := filepath.Abs(filepath.Join(baseDir, "plugin.wasm"))
+	assert.NilError(t, err)
+	assert.NilError(t, Close(v))
+	errs = reloaded.Validate(unmarshaler.RuleGroup{}, rulefmt.Rule{Alert: "Foo", Labels: map[string]string{"severity": "critical"}}, nil)
+	assert.Equal(t, len(errs), 1, "unexpected errors: %s", errs)
+	assert.NilError(t, Close(reloaded))
+	_, err = v.(*wasmPlugin).module.instantiate(context.Background())
+	assert.ErrorContains(t, err, "closed")
+	assert.Equal(t, wasmModules[absPath], changed.(*wasmPlugin).module)
+	assert.NilError(t, Close(&Logical{Operator: config.NotOperator, Operands: []Operand{{Validator: changed}}}))
+	_, ok := wasmModules[absPath]
+	assert.Assert(t, !ok)
+
+	validatorConfig.BaseDir = t.TempDir()
+	_, err = NewFromConfig(config.AlertScope, validatorConfig)
+	assert.ErrorContains(t, err, "failed to read WASM plugin")
 }
 
 func Test(t *testing.T) {
@@ -379,3 +604,42 @@ func Test(t *testing.T) {
 		})
 	}
 }
+
+// activityMatrix returns a matrix with a series for each of the activities, `1` means the series is present at the step, `0` it is absent.
+func activityMatrix(step time.Duration, activities ...string) model.Matrix {
+	matrix := model.Matrix{}
+	for i, activity := range activities {
+		series := &model.SampleStream{Metric: model.Metric{"series": model.LabelValue(fmt.Sprint(i))}}
+		for j, active := range activity {
+			if active == '1' {
+				series.Values = append(series.Values, model.SamplePair{Timestamp: model.TimeFromUnixNano(int64(time.Duration(j) * step)), Value: 1})
+			}
+		}
+		matrix = append(matrix, series)
+	}
+	return matrix
+}
+
+func TestSimulateAlert(t *testing.T) {
+	tests := []struct {
+		name          string
+		activities    []string
+		forDuration   time.Duration
+		keepFiringFor time.Duration
+		expected      alertSimulation
+	}{
+		{name: "noData", activities: []string{}, expected: alertSimulation{}},
+		{name: "firingWithoutFor", activities: []string{"111"}, expected: alertSimulation{firings: 1, firingDuration: 3 * time.Minute, longestFiring: 3 * time.Minute, series: 1}},
+		{name: "onlyPending", activities: []string{"11"}, forDuration: 2 * time.Minute, expected: alertSimulation{}},
+		{name: "firingAfterFor", activities: []string{"1110111"}, forDuration: 2 * time.Minute, expected: alertSimulation{firings: 2, firingDuration: 2 * time.Minute, longestFiring: time.Minute, series: 1}},
+		{name: "keptFiring", activities: []string{"1001"}, keepFiringFor: 2 * time.Minute, expected: alertSimulation{firings: 1, firingDuration: 4 * time.Minute, longestFiring: 4 * time.Minute, series: 1}},
+		{name: "resolvedAfterKeepFiringFor", activities: []string{"10001"}, keepFiringFor: 2 * time.Minute, expected: alertSimulation{firings: 2, firingDuration: 4 * time.Minute, longestFiring: 3 * time.Minute, series: 1}},
+		{name: "keepFiringForRoundedToStep", activities: []string{"10001"}, keepFiringFor: 90 * time.Second, expected: alertSimulation{firings: 2, firingDuration: 4 * time.Minute, longestFiring: 3 * time.Minute, series: 1}},
+		{name: "multipleSeries", activities: []string{"101", "0000", "1"}, expected: alertSimulation{firings: 3, firingDuration: 3 * time.Minute, longestFiring: time.Minute, series: 2}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, simulateAlert(activityMatrix(time.Minute, tt.activities...), time.Minute, tt.forDuration, tt.keepFiringFor))
+		})
+	}
+}
@@ -0,0 +1,270 @@
+package validator
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/fusakla/promruval/v3/pkg/prometheus"
+	"github.com/fusakla/promruval/v3/pkg/unmarshaler"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/model/rulefmt"
+	log "github.com/sirupsen/logrus"
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
+)
+
+const (
+	defaultWasmTimeout = 10 * time.Second
+
+	// Functions exported by the WASM plugin, see the docs/validations.md for the description of the ABI.
+	wasmAllocateFunction = "promruval_allocate"
+	wasmValidateFunction = "promruval_validate"
+	wasmFreeFunction     = "promruval_free"
+)
+
+type wasmPluginParams struct {
+	File    string         `yaml:"file"`
+	Timeout model.Duration `yaml:"timeout"`
+
+	resolvedFile string
+}
+
+func (p *wasmPluginParams) ResolveRelativePaths(baseDir string) error {
+	if filepath.IsAbs(p.File) {
+		return fmt.Errorf("WASM plugin file path must be relative to the config file, got %s", p.File)
+	}
+	p.resolvedFile = filepath.Join(baseDir, p.File)
+	return nil
+}
+
+func newWasmPlugin(unmarshal UnmarshalParamsFunc) (Validator, error) {
+	params := wasmPluginParams{}
+	if err := unmarshal(&params); err != nil {
+		return nil, err
+	}
+	if params.File == "" {
+		return nil, fmt.Errorf("missing file")
+	}
+	if params.resolvedFile == "" {
+		if err := params.ResolveRelativePaths(""); err != nil {
+			return nil, err
+		}
+	}
+	if params.Timeout < 0 {
+		return nil, fmt.Errorf("timeout must not be negative")
+	}
+	timeout := time.Duration(params.Timeout)
+	if timeout == 0 {
+		timeout = defaultWasmTimeout
+	}
+	wasm, err := os.ReadFile(params.resolvedFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read WASM plugin: %w", err)
+	}
+	module, err := loadWasmModule(context.Background(), params.File, params.resolvedFile, wasm)
+	if err != nil {
+		return nil, err
+	}
+	return &wasmPlugin{file: params.File, module: module, timeout: timeout}, nil
+}
+
+// wasmModules are shared by all the plugins loaded from the same file content, so reloading the config in the watch mode does not compile the module again.
+// The modules are reference counted and closed once all the plugins using them are closed.
+var (
+	wasmModules    = map[string]*wasmModule{}
+	wasmModulesMtx sync.Mutex
+)
+
+// wasmModule is the compiled WASM plugin with a pool of its instances, since the instances are not safe for concurrent use.
+type wasmModule struct {
+	file      string
+	absPath   string
+	hash      [sha256.Size]byte
+	refs      int
+	runtime   wazero.Runtime
+	compiled  wazero.CompiledModule
+	instances chan api.Module
+}
+
+// loadWasmModule returns the already loaded module of the file, it is compiled again only if the file content changed.
+// The previous version of the module is kept until all the plugins using it are closed.
+func loadWasmModule(ctx context.Context, file, resolvedFile string, wasm []byte) (*wasmModule, error) {
+	absPath, err := filepath.Abs(resolvedFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve WASM plugin path %s: %w", file, err)
+	}
+	hash := sha256.Sum256(wasm)
+	wasmModulesMtx.Lock()
+	defer wasmModulesMtx.Unlock()
+	if previous, ok := wasmModules[absPath]; ok && previous.hash == hash {
+		previous.refs++
+		return previous, nil
+	}
+	wasmRuntime := wazero.NewRuntimeWithConfig(ctx, wazero.NewRuntimeConfig().WithCloseOnContextDone(true))
+	if _, err := wasi_snapshot_preview1.Instantiate(ctx, wasmRuntime); err != nil {
+		_ = wasmRuntime.Close(ctx)
+		return nil, fmt.Errorf("failed to instantiate WASI: %w", err)
+	}
+	module, err := compileWasmModule(ctx, wasmRuntime, file, wasm)
+	if err != nil {
+		_ = wasmRuntime.Close(ctx)
+		return nil, err
+	}
+	module.absPath = absPath
+	module.hash = hash
+	module.refs = 1
+	wasmModules[absPath] = module
+	return module, nil
+}
+
+// unref releases the module used by a closed plugin, the last one closes the runtime with all the instances.
+func (m *wasmModule) unref(ctx context.Context) error {
+	wasmModulesMtx.Lock()
+	defer wasmModulesMtx.Unlock()
+	m.refs--
+	if m.refs > 0 {
+		return nil
+	}
+	if wasmModules[m.absPath] == m {
+		delete(wasmModules, m.absPath)
+	}
+	return m.runtime.Close(ctx)
+}
+
+func compileWasmModule(ctx context.Context, wasmRuntime wazero.Runtime, file string, wasm []byte) (*wasmModule, error) {
+	compiled, err := wasmRuntime.CompileModule(ctx, wasm)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compile WASM plugin %s: %w", file, err)
+	}
+	for _, f := range []string{wasmAllocateFunction, wasmValidateFunction} {
+		if _, ok := compiled.ExportedFunctions()[f]; !ok {
+			_ = compiled.Close(ctx)
+			return nil, fmt.Errorf("WASM plugin %s does not export the required function %s", file, f)
+		}
+	}
+	module := &wasmModule{
+		file:      file,
+		runtime:   wasmRuntime,
+		compiled:  compiled,
+		instances: make(chan api.Module, runtime.NumCPU()),
+	}
+	// Instantiate the module right away, so the invalid plugin fails the config loading.
+	instance, err := module.instantiate(ctx)
+	if err != nil {
+		_ = compiled.Close(ctx)
+		return nil, err
+	}
+	module.release(ctx, instance)
+	return module, nil
+}
+
+func (m *wasmModule) instantiate(ctx context.Context) (api.Module, error) {
+	moduleConfig := wazero.NewModuleConfig().WithName("").WithStartFunctions("_initialize").WithStderr(os.Stderr)
+	instance, err := m.runtime.InstantiateModule(ctx, m.compiled, moduleConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to instantiate WASM plugin %s: %w", m.file, err)
+	}
+	return instance, nil
+}
+
+func (m *wasmModule) acquire(ctx context.Context) (api.Module, error) {
+	select {
+	case instance := <-m.instances:
+		return instance, nil
+	default:
+		return m.instantiate(ctx)
+	}
+}
+
+func (m *wasmModule) release(ctx context.Context, instance api.Module) {
+	if instance.IsClosed() {
+		return
+	}
+	select {
+	case m.instances <- instance:
+	default:
+		_ = instance.Close(ctx)
+	}
+}
+
+// wasmPlugin runs the WASM module in a sandbox without access to the filesystem or network.
+type wasmPlugin struct {
+	file    string
+	module  *wasmModule
+	timeout time.Duration
+}
+
+// Close releases the WASM module, the plugin must not be used afterwards.
+func (h *wasmPlugin) Close() error {
+	return h.module.unref(context.Background())
+}
+
+func (h *wasmPlugin) String() string {
+	return fmt.Sprintf("passes the check of the WASM plugin `%s`", h.file)
+}
+
+func (h *wasmPlugin) Validate(group unmarshaler.RuleGroup, rule rulefmt.Rule, _ *prometheus.Client) []error {
+	input, err := json.Marshal(newPolicyInput(group, rule))
+	if err != nil {
+		return []error{fmt.Errorf("failed to marshal the input of the WASM plugin `%s`: %w", h.file, err)}
+	}
+	instance, err := h.module.acquire(context.Background())
+	if err != nil {
+		return []error{err}
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), h.timeout)
+	defer cancel()
+	output, err := h.call(ctx, instance, input)
+	h.module.release(context.Background(), instance)
+	if err != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return []error{fmt.Errorf("WASM plugin `%s` timed out after %s", h.file, h.timeout)}
+		}
+		return []error{fmt.Errorf("WASM plugin `%s` failed: %w", h.file, err)}
+	}
+	errs, err := policyOutputErrors(output, log.Fields{"plugin": h.file, "group": group.Name, "rule": ruleName(rule)})
+	if err != nil {
+		return []error{fmt.Errorf("invalid output of the WASM plugin `%s`: %w", h.file, err)}
+	}
+	return errs
+}
+
+// call passes the input to the plugin memory allocated by the plugin and reads the output from the location returned by the validate function.
+func (h *wasmPlugin) call(ctx context.Context, instance api.Module, input []byte) ([]byte, error) {
+	res, err := instance.ExportedFunction(wasmAllocateFunction).Call(ctx, uint64(len(input)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to allocate memory: %w", err)
+	}
+	inputPtr := uint32(res[0])
+	if !instance.Memory().Write(inputPtr, input) {
+		return nil, fmt.Errorf("allocated memory at %d of size %d is out of range", inputPtr, len(input))
+	}
+	res, err = instance.ExportedFunction(wasmValidateFunction).Call(ctx, uint64(inputPtr), uint64(len(input)))
+	if err != nil {
+		return nil, err
+	}
+	outputPtr, outputLen := uint32(res[0]>>32), uint32(res[0])
+	view, ok := instance.Memory().Read(outputPtr, outputLen)
+	if !ok {
+		return nil, fmt.Errorf("returned output at %d of size %d is out of range", outputPtr, outputLen)
+	}
+	output := make([]byte, len(view))
+	copy(output, view)
+	if free := instance.ExportedFunction(wasmFreeFunction); free != nil {
+		for _, ptr := range []uint32{inputPtr, outputPtr} {
+			if _, err := free.Call(ctx, uint64(ptr)); err != nil {
+				return nil, fmt.Errorf("failed to free memory: %w", err)
+			}
+		}
+	}
+	return output, nil
+}